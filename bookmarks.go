@@ -0,0 +1,140 @@
+package neoql
+
+import (
+	"gopkg.in/packstream.v1"
+	"sync"
+)
+
+// Bookmarker is implemented by the neoql connections, so the bookmark manager shared by the connections of a sql.DB
+// can be reached through the Raw() method of a sql.Conn.
+type Bookmarker interface {
+	BookmarkManager() *BookmarkManager
+}
+
+// BookmarkManager keeps the bookmarks of the transactions committed through the connections opened with the same
+// connection string. These bookmarks are sent when beginning a transaction, or running a query outside of a
+// transaction, so the server waits until these transactions are applied before running the new ones. Hence, a query
+// following a write can read its result, even when both run on distinct connections.
+// The bookmarks are kept by database, since the server rejects bookmarks of distinct databases: only the bookmarks of
+// the database a transaction runs against are sent.
+// A BookmarkManager is safe for concurrent use.
+type BookmarkManager struct {
+	mu        sync.Mutex
+	database  string              // database of the connection string, "" for the server default database.
+	bookmarks map[string][]string // bookmarks, by database.
+}
+
+// Bookmarks returns the bookmarks currently held by the manager for the database of the connection string.
+func (m *BookmarkManager) Bookmarks() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.bookmarks[m.database]...)
+}
+
+// DatabaseBookmarks returns the bookmarks currently held by the manager for the "database" database, "" being the
+// server default database.
+func (m *BookmarkManager) DatabaseBookmarks(database string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.bookmarks[database]...)
+}
+
+// AddBookmarks adds bookmarks of the database of the connection string to the manager, for instance bookmarks
+// received from another process, so the next transactions wait for them.
+func (m *BookmarkManager) AddBookmarks(bookmarks ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, bookmark := range bookmarks {
+		m.add(m.database, bookmark)
+	}
+}
+
+// AddDatabaseBookmarks adds bookmarks of the "database" database to the manager, "" being the server default
+// database.
+func (m *BookmarkManager) AddDatabaseBookmarks(database string, bookmarks ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, bookmark := range bookmarks {
+		m.add(database, bookmark)
+	}
+}
+
+// setDatabase sets the database of the connection string, whose bookmarks are handled by Bookmarks and AddBookmarks.
+func (m *BookmarkManager) setDatabase(database string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.database = database
+}
+
+// update replaces the "previous" bookmarks of the "database" database, sent when beginning a transaction, by the
+// "bookmark" of the committed transaction, which causally follows them.
+func (m *BookmarkManager) update(database string, previous []string, bookmark string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var kept []string
+	for _, b := range m.bookmarks[database] {
+		if !containsString(previous, b) {
+			kept = append(kept, b)
+		}
+	}
+	if m.bookmarks != nil {
+		m.bookmarks[database] = kept
+	}
+	m.add(database, bookmark)
+}
+
+// add adds a bookmark of the "database" database, unless it is empty or already held. The mutex must be held by the
+// caller.
+func (m *BookmarkManager) add(database, bookmark string) {
+	if bookmark == "" || containsString(m.bookmarks[database], bookmark) {
+		return
+	}
+	if m.bookmarks == nil {
+		m.bookmarks = make(map[string][]string)
+	}
+	m.bookmarks[database] = append(m.bookmarks[database], bookmark)
+}
+
+// containsString returns true if "s" is in the "list" slice.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// BookmarkManager implements the Bookmarker interface.
+func (c *conn) BookmarkManager() *BookmarkManager {
+	return c.bookmarks
+}
+
+// sendBookmarks adds the bookmarks to wait for in the "extra" metadata of a BEGIN message, or a RUN message outside of
+// a transaction. These are the bookmarks of the manager for the database set in "extra", and the additional
+// "bookmarks". The bookmarks sent are remembered, so the manager replaces them by the bookmark of the transaction once
+// committed.
+// Bookmarks require Bolt v3 or above.
+func (c *conn) sendBookmarks(extra map[string]interface{}, bookmarks []string) {
+	c.sentDatabase, _ = extra["db"].(string)
+	if c.bookmarks != nil {
+		bookmarks = append(c.bookmarks.DatabaseBookmarks(c.sentDatabase), bookmarks...)
+	}
+	c.sent = bookmarks
+	if len(bookmarks) != 0 {
+		extra["bookmarks"] = bookmarks
+	}
+}
+
+// receiveBookmark reads the bookmark of a committed transaction from the "st" summary, and hands it to the manager.
+func (c *conn) receiveBookmark(st *packstream.Structure) {
+	if len(st.Fields) == 0 {
+		return
+	}
+	if m, ok := st.Fields[0].(map[string]interface{}); ok {
+		if bookmark, ok := m["bookmark"].(string); ok && c.bookmarks != nil {
+			c.bookmarks.update(c.sentDatabase, c.sent, bookmark)
+		}
+	}
+	c.sent = nil
+}
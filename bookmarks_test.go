@@ -0,0 +1,132 @@
+package neoql
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"gopkg.in/packstream.v1"
+	"reflect"
+	"testing"
+)
+
+func TestBookmarkManager(t *testing.T) {
+	m := new(BookmarkManager)
+	m.AddBookmarks("a", "b", "a", "")
+	if bookmarks := m.Bookmarks(); !reflect.DeepEqual(bookmarks, []string{"a", "b"}) {
+		t.Errorf("invalid bookmarks, expected %v got %v.", []string{"a", "b"}, bookmarks)
+	}
+
+	m.update("", []string{"a"}, "c")
+	if bookmarks := m.Bookmarks(); !reflect.DeepEqual(bookmarks, []string{"b", "c"}) {
+		t.Errorf("invalid bookmarks, expected %v got %v.", []string{"b", "c"}, bookmarks)
+	}
+
+	// The bookmarks are kept by database, Bookmarks returning those of the connection string database.
+	m.AddDatabaseBookmarks("sales", "d")
+	m.update("hr", []string{"b"}, "e")
+	if bookmarks := m.Bookmarks(); !reflect.DeepEqual(bookmarks, []string{"b", "c"}) {
+		t.Errorf("invalid bookmarks, expected %v got %v.", []string{"b", "c"}, bookmarks)
+	} else if bookmarks = m.DatabaseBookmarks("sales"); !reflect.DeepEqual(bookmarks, []string{"d"}) {
+		t.Errorf("invalid bookmarks, expected %v got %v.", []string{"d"}, bookmarks)
+	} else if bookmarks = m.DatabaseBookmarks("hr"); !reflect.DeepEqual(bookmarks, []string{"e"}) {
+		t.Errorf("invalid bookmarks, expected %v got %v.", []string{"e"}, bookmarks)
+	}
+	m.setDatabase("sales")
+	if bookmarks := m.Bookmarks(); !reflect.DeepEqual(bookmarks, []string{"d"}) {
+		t.Errorf("invalid bookmarks, expected %v got %v.", []string{"d"}, bookmarks)
+	}
+}
+
+func TestNeoDriver_bookmarkManager(t *testing.T) {
+	d := new(neoDriver)
+	if d.bookmarkManager("a") != d.bookmarkManager("a") {
+		t.Error("connections opened with the same connection string should share their bookmark manager.")
+	} else if d.bookmarkManager("a") == d.bookmarkManager("b") {
+		t.Error("connections opened with distinct connection strings should not share their bookmark manager.")
+	}
+}
+
+func TestConn_bookmarks(t *testing.T) {
+	var (
+		wr bytes.Buffer
+		rd bytes.Buffer
+	)
+	c := testMockConn(t, &rd, &wr)
+	defer c.Close()
+	c.version = 3
+	c.bookmarks = new(BookmarkManager)
+	c.bookmarks.AddBookmarks("a")
+
+	// The bookmarks are sent when beginning a transaction, then replaced by the transaction bookmark.
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"bookmark": "c"})))
+	data := testGetEncodedMessage(t, packstream.NewStructure(byteBegin, map[string]interface{}{"bookmarks": []string{"a", "b"}}))
+	data = append(data, testGetEncodedMessage(t, packstream.NewStructure(byteCommit))...)
+	if tx, err := c.BeginTx(WithBookmarks(context.Background(), "b"), driver.TxOptions{}); err != nil {
+		t.Error(err)
+	} else if err = tx.Commit(); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(wr.Bytes(), data) {
+		t.Errorf("unexpected messages, expected %# x got %# x.", data, wr.Bytes())
+	} else if bookmarks := c.BookmarkManager().Bookmarks(); !reflect.DeepEqual(bookmarks, []string{"c"}) {
+		t.Errorf("invalid bookmarks, expected %v got %v.", []string{"c"}, bookmarks)
+	}
+
+	// A query outside of a transaction sends the bookmarks, and its summary carries its bookmark.
+	wr.Reset()
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{}})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"bookmark": "d"})))
+	data = testGetEncodedMessage(t, packstream.NewStructure(byteRun, "CREATE (n)", map[string]interface{}{}, map[string]interface{}{"bookmarks": []string{"c"}}))
+	data = append(data, testGetEncodedMessage(t, packstream.NewStructure(byteDiscardAll))...)
+	if _, err := c.exec(context.Background(), "CREATE (n)", map[string]interface{}{}); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(wr.Bytes(), data) {
+		t.Errorf("unexpected messages, expected %# x got %# x.", data, wr.Bytes())
+	} else if bookmarks := c.BookmarkManager().Bookmarks(); !reflect.DeepEqual(bookmarks, []string{"d"}) {
+		t.Errorf("invalid bookmarks, expected %v got %v.", []string{"d"}, bookmarks)
+	}
+}
+
+func TestConn_databaseBookmarks(t *testing.T) {
+	var (
+		wr bytes.Buffer
+		rd bytes.Buffer
+	)
+	c := testMockConn(t, &rd, &wr)
+	defer c.Close()
+	c.version = 0x0404
+	c.database = "sales"
+	c.bookmarks = new(BookmarkManager)
+	c.bookmarks.AddDatabaseBookmarks("sales", "a")
+	c.bookmarks.AddDatabaseBookmarks("hr", "b")
+
+	// Only the bookmarks of the target database are sent, and the bookmark received replaces them.
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{}})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"bookmark": "c"})))
+	extra := map[string]interface{}{"db": "hr", "bookmarks": []interface{}{"b"}}
+	if _, err := c.exec(WithDatabase(context.Background(), "hr"), "CREATE (n)", map[string]interface{}{}); err != nil {
+		t.Error(err)
+	} else if run := testDecodeMessage(t, wr.Bytes()); !reflect.DeepEqual(run.Fields[2], extra) {
+		// The RUN metadata has several keys, whose encoding order is random, so the message is compared decoded.
+		t.Errorf("unexpected RUN metadata, expected %v got %v.", extra, run.Fields[2])
+	} else if bookmarks := c.bookmarks.DatabaseBookmarks("hr"); !reflect.DeepEqual(bookmarks, []string{"c"}) {
+		t.Errorf("invalid bookmarks, expected %v got %v.", []string{"c"}, bookmarks)
+	} else if bookmarks = c.bookmarks.DatabaseBookmarks("sales"); !reflect.DeepEqual(bookmarks, []string{"a"}) {
+		t.Errorf("invalid bookmarks, expected %v got %v.", []string{"a"}, bookmarks)
+	}
+
+	// The connection string database is used when ctx does not select one.
+	wr.Reset()
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{}})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"bookmark": "d"})))
+	extra = map[string]interface{}{"db": "sales", "bookmarks": []interface{}{"a"}}
+	if _, err := c.exec(context.Background(), "CREATE (n)", map[string]interface{}{}); err != nil {
+		t.Error(err)
+	} else if run := testDecodeMessage(t, wr.Bytes()); !reflect.DeepEqual(run.Fields[2], extra) {
+		t.Errorf("unexpected RUN metadata, expected %v got %v.", extra, run.Fields[2])
+	} else if bookmarks := c.bookmarks.DatabaseBookmarks("sales"); !reflect.DeepEqual(bookmarks, []string{"d"}) {
+		t.Errorf("invalid bookmarks, expected %v got %v.", []string{"d"}, bookmarks)
+	} else if bookmarks = c.bookmarks.DatabaseBookmarks("hr"); !reflect.DeepEqual(bookmarks, []string{"c"}) {
+		t.Errorf("invalid bookmarks, expected %v got %v.", []string{"c"}, bookmarks)
+	}
+}
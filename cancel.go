@@ -0,0 +1,108 @@
+package neoql
+
+import (
+	"context"
+	"errors"
+	"gopkg.in/packstream.v1"
+	"net"
+	"time"
+)
+
+// errInterrupted is returned when flushing requests once the current one has been interrupted. It is replaced by the
+// context error when the request stops being watched.
+var errInterrupted = errors.New("neoql: The request has been interrupted")
+
+// watch watches ctx while the responses to a request are read, and interrupts the request once ctx is done. The
+// returned function must be called once the responses are read: it stops watching ctx, and returns the ctx error if
+// the request has been interrupted.
+// On Bolt v3 and above, the request is interrupted with a RESET message, the connection being ready again once its
+// response is read. On Bolt v1, the pending read is interrupted, and the connection is defunct.
+func (c *conn) watch(ctx context.Context) func() error {
+	done := ctx.Done()
+	if done == nil {
+		return func() error { return nil }
+	}
+	stopped := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		select {
+		case <-stopped:
+		case <-done:
+			c.interrupt()
+		}
+	}()
+	return func() error {
+		close(stopped)
+		<-finished
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if !c.interrupted {
+			return nil
+		}
+		c.interrupted = false
+		if c.resetSent && c.state != stateDefunct {
+			// The RESET message has been written after the requests in flight, so its response is read last.
+			c.inflight = append(c.inflight, byteReset)
+			c.state = stateInterrupted
+		} else {
+			c.state = stateDefunct
+		}
+		c.resetSent = false
+		return ctx.Err()
+	}
+}
+
+// watchConnect watches ctx while a connection is opened, and expires the deadline of the "netConn" network connection
+// once ctx is done, so the pending handshake or authentication is interrupted even if ctx has no deadline. The
+// returned function must be called once the connection is opened: it stops watching ctx, and returns the ctx error if
+// the connection has been interrupted.
+func watchConnect(ctx context.Context, netConn net.Conn) func() error {
+	done := ctx.Done()
+	if done == nil {
+		return func() error { return nil }
+	}
+	stopped := make(chan struct{})
+	finished := make(chan struct{})
+	interrupted := false
+	go func() {
+		defer close(finished)
+		select {
+		case <-stopped:
+		case <-done:
+			interrupted = true
+			netConn.SetDeadline(time.Now())
+		}
+	}()
+	return func() error {
+		close(stopped)
+		<-finished
+		if interrupted {
+			return ctx.Err()
+		}
+		return nil
+	}
+}
+
+// interrupt is called by the goroutine watching the context of a request, once it is done. It writes a RESET message
+// on Bolt v3 and above, so the server terminates the request. Otherwise, or if the message cannot be written, it
+// interrupts the pending read.
+func (c *conn) interrupt() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interrupted = true
+	if c.atLeast(3, 0) {
+		wr := NewWriter(c.conn)
+		encoded, err := packstream.Marshal(packstream.NewStructure(byteReset))
+		if err == nil {
+			if _, err = wr.Write(encoded); err == nil {
+				err = wr.Flush(true)
+			}
+		}
+		if c.resetSent = err == nil; c.resetSent {
+			return
+		}
+	}
+	c.conn.SetDeadline(time.Now())
+}
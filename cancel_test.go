@@ -0,0 +1,88 @@
+package neoql
+
+import (
+	"context"
+	"gopkg.in/packstream.v1"
+	"net"
+	"testing"
+)
+
+// testPipeConn returns a connection using the "version" protocol version, whose server side is "server".
+func testPipeConn(t *testing.T, version uint32) (c *conn, server net.Conn) {
+	client, server := net.Pipe()
+	c = &conn{wr: NewWriter(client), rd: NewReader(client), conn: client, version: version, state: stateReady}
+	c.fetchSize = -1
+	return c, server
+}
+
+// testServe reads "n" messages on the server side of a connection, then calls "cancel", and writes the "responses".
+func testServe(t *testing.T, server net.Conn, n int, cancel func(), responses ...*packstream.Structure) {
+	rd := NewReader(server)
+	wr := NewWriter(server)
+	for i := 0; i < n; i++ {
+		if _, err := rd.ReadMessage(); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	cancel()
+	if len(responses) == 0 {
+		return
+	}
+	if message, err := rd.ReadMessage(); err != nil {
+		t.Error(err)
+		return
+	} else if st := new(packstream.Structure); packstream.Unmarshal(message, &st) != nil || st.Signature != byteReset {
+		t.Errorf("unexpected message, expected RESET got %# x.", message)
+		return
+	}
+	for _, res := range responses {
+		encoded, _ := packstream.Marshal(res)
+		wr.Write(encoded)
+		if err := wr.Flush(true); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+}
+
+func TestConn_watch(t *testing.T) {
+	c, server := testPipeConn(t, 3)
+	defer server.Close()
+
+	// The query is interrupted with a RESET message, then the connection is ready again.
+	ctx, cancel := context.WithCancel(context.Background())
+	go testServe(t, server, 2, cancel,
+		packstream.NewStructure(byteFailure, map[string]interface{}{"code": "Neo.ClientError.Transaction.Terminated", "message": "terminated"}),
+		packstream.NewStructure(byteIgnored),
+		packstream.NewStructure(byteSuccess, map[string]interface{}{}))
+	if _, err := c.exec(ctx, "CALL apoc.util.sleep(60000)", map[string]interface{}{}); err != context.Canceled {
+		t.Errorf("error should be %v, got %v.", context.Canceled, err)
+	} else if err = c.ready(); err != nil {
+		t.Error(err)
+	} else if c.state != stateReady || len(c.inflight) != 0 {
+		t.Errorf("connection should be ready, got %v with %d requests in flight.", c.state, len(c.inflight))
+	}
+
+	// An uninterrupted query does not send any RESET message.
+	if stop := c.watch(context.Background()); stop() != nil {
+		t.Error("error should be nil when the context is not done.")
+	}
+	if _, err := c.run(ctx, "RETURN 1", map[string]interface{}{}, false); err != context.Canceled {
+		t.Errorf("error should be %v when the context is already done, got %v.", context.Canceled, err)
+	}
+}
+
+func TestConn_watch_v1(t *testing.T) {
+	c, server := testPipeConn(t, 1)
+	defer server.Close()
+
+	// On Bolt v1, the pending read is interrupted, so the connection is defunct.
+	ctx, cancel := context.WithCancel(context.Background())
+	go testServe(t, server, 2, cancel)
+	if _, err := c.exec(ctx, "RETURN 1", map[string]interface{}{}); err != context.Canceled {
+		t.Errorf("error should be %v, got %v.", context.Canceled, err)
+	} else if c.state != stateDefunct {
+		t.Errorf("connection should be defunct, got %v.", c.state)
+	}
+}
@@ -0,0 +1,106 @@
+package neoql
+
+import (
+	"gopkg.in/neoql.v1/types"
+	"io"
+	"reflect"
+	"time"
+)
+
+// columnType is the type of a result column, as inferred from its values.
+type columnType struct {
+	name     string       // name is the Cypher type name, like "NODE" or "INTEGER".
+	scanType reflect.Type // scanType is the Go type the values can be scanned into.
+}
+
+// anyType is the type of a column whose type is unknown, because its value is null or the result has no record.
+var anyType = columnType{"ANY", reflect.TypeOf((*interface{})(nil)).Elem()}
+
+// valueType returns the type of a value decoded from a record.
+func valueType(v interface{}) columnType {
+	switch v.(type) {
+	case bool:
+		return columnType{"BOOLEAN", reflect.TypeOf(false)}
+	case int64:
+		return columnType{"INTEGER", reflect.TypeOf(int64(0))}
+	case float64:
+		return columnType{"FLOAT", reflect.TypeOf(float64(0))}
+	case string:
+		return columnType{"STRING", reflect.TypeOf("")}
+	case []byte:
+		return columnType{"BYTES", reflect.TypeOf([]byte(nil))}
+	case []interface{}:
+		return columnType{"LIST", reflect.TypeOf(types.List(nil))}
+	case map[string]interface{}:
+		// types.Map is not a scan type, since its Scan method writes into the map, which is nil once allocated by
+		// reflect.New.
+		return columnType{"MAP", reflect.TypeOf(map[string]interface{}(nil))}
+	case time.Time:
+		return columnType{"DATETIME", reflect.TypeOf(time.Time{})}
+	case types.Date:
+		return columnType{"DATE", reflect.TypeOf(time.Time{})}
+	case types.OffsetTime:
+		return columnType{"TIME", reflect.TypeOf(time.Time{})}
+	case types.LocalTime:
+		return columnType{"LOCAL TIME", reflect.TypeOf(time.Time{})}
+	case types.LocalDateTime:
+		return columnType{"LOCAL DATETIME", reflect.TypeOf(time.Time{})}
+	case *types.Duration:
+		return columnType{"DURATION", reflect.TypeOf(types.Duration{})}
+	case *types.Point2D:
+		return columnType{"POINT", reflect.TypeOf(types.Point2D{})}
+	case *types.Point3D:
+		return columnType{"POINT", reflect.TypeOf(types.Point3D{})}
+	case *types.Node:
+		return columnType{"NODE", reflect.TypeOf(types.Node{})}
+	case *types.Relationship:
+		return columnType{"RELATIONSHIP", reflect.TypeOf(types.Relationship{})}
+	case *types.UnboundRelationship:
+		return columnType{"RELATIONSHIP", reflect.TypeOf(types.UnboundRelationship{})}
+	case *types.Path:
+		return columnType{"PATH", reflect.TypeOf(types.Path{})}
+	}
+	return anyType
+}
+
+// columnType returns the type of the column "index", inferred from its value in the first record. If no record has
+// been read yet, the first one is read from the connection and kept in Rows.
+func (r *statementResult) columnType(index int) columnType {
+	if r.first == nil && r.streaming {
+		record, err := r.fetch(false)
+		if err == nil {
+			row := make(map[string]interface{}, len(r.Fields))
+			for i, f := range r.Fields {
+				row[f] = record[i]
+			}
+			r.Rows = append(r.Rows, row)
+		} else if err != io.EOF {
+			r.err = err
+		}
+	}
+	if index < 0 || index >= len(r.first) {
+		return anyType
+	}
+	return valueType(r.first[index])
+}
+
+// ColumnTypeDatabaseTypeName implements the ColumnTypeDatabaseTypeName() method of the
+// sql/driver.RowsColumnTypeDatabaseTypeName interface.
+// Cypher results are not typed, so the type is inferred from the value of the column in the first record, like
+// "NODE", "LIST" or "INTEGER". It is "ANY" if this value is null or the result has no record.
+func (r *statementResult) ColumnTypeDatabaseTypeName(index int) string {
+	return r.columnType(index).name
+}
+
+// ColumnTypeScanType implements the ColumnTypeScanType() method of the sql/driver.RowsColumnTypeScanType interface.
+// The type is inferred from the value of the column in the first record, like types.Node for a node. It is the empty
+// interface type if this value is null or the result has no record.
+func (r *statementResult) ColumnTypeScanType(index int) reflect.Type {
+	return r.columnType(index).scanType
+}
+
+// ColumnTypeNullable implements the ColumnTypeNullable() method of the sql/driver.RowsColumnTypeNullable interface.
+// Any Cypher value may be null, so the columns are always nullable.
+func (r *statementResult) ColumnTypeNullable(index int) (nullable, ok bool) {
+	return true, true
+}
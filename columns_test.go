@@ -0,0 +1,99 @@
+package neoql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"gopkg.in/neoql.v1/types"
+	"gopkg.in/packstream.v1"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValueType(t *testing.T) {
+	for _, test := range []struct {
+		value    interface{}
+		name     string
+		scanType reflect.Type
+	}{
+		{nil, "ANY", reflect.TypeOf((*interface{})(nil)).Elem()},
+		{true, "BOOLEAN", reflect.TypeOf(false)},
+		{int64(1), "INTEGER", reflect.TypeOf(int64(0))},
+		{1.5, "FLOAT", reflect.TypeOf(float64(0))},
+		{"a", "STRING", reflect.TypeOf("")},
+		{[]byte{1}, "BYTES", reflect.TypeOf([]byte(nil))},
+		{[]interface{}{int64(1)}, "LIST", reflect.TypeOf(types.List(nil))},
+		{map[string]interface{}{"a": int64(1)}, "MAP", reflect.TypeOf(map[string]interface{}(nil))},
+		{time.Unix(1, 0), "DATETIME", reflect.TypeOf(time.Time{})},
+		{types.Date(time.Unix(0, 0)), "DATE", reflect.TypeOf(time.Time{})},
+		{types.OffsetTime(time.Unix(0, 0)), "TIME", reflect.TypeOf(time.Time{})},
+		{types.LocalTime(time.Unix(0, 0)), "LOCAL TIME", reflect.TypeOf(time.Time{})},
+		{types.LocalDateTime(time.Unix(0, 0)), "LOCAL DATETIME", reflect.TypeOf(time.Time{})},
+		{&types.Duration{Days: 1}, "DURATION", reflect.TypeOf(types.Duration{})},
+		{&types.Point2D{SRID: 7203, X: 1, Y: 2}, "POINT", reflect.TypeOf(types.Point2D{})},
+		{&types.Point3D{SRID: 9157, X: 1, Y: 2, Z: 3}, "POINT", reflect.TypeOf(types.Point3D{})},
+		{new(types.Node), "NODE", reflect.TypeOf(types.Node{})},
+		{new(types.Relationship), "RELATIONSHIP", reflect.TypeOf(types.Relationship{})},
+		{new(types.UnboundRelationship), "RELATIONSHIP", reflect.TypeOf(types.UnboundRelationship{})},
+		{new(types.Path), "PATH", reflect.TypeOf(types.Path{})},
+	} {
+		typ := valueType(test.value)
+		if typ.name != test.name {
+			t.Errorf("invalid type name of %v, expected %v got %v.", test.value, test.name, typ.name)
+		} else if typ.scanType != test.scanType {
+			t.Errorf("invalid scan type of %v, expected %v got %v.", test.value, test.scanType, typ.scanType)
+		}
+
+		// The value must be scannable into a new value of the scan type, like database/sql does: with its Scan
+		// method, or else by assigning or converting the value.
+		dest := reflect.New(typ.scanType)
+		if scanner, ok := dest.Interface().(sql.Scanner); ok {
+			if err := scanner.Scan(test.value); err != nil {
+				t.Errorf("failed to scan %v into %v: %v", test.value, typ.scanType, err)
+			}
+		} else if test.value != nil && !reflect.TypeOf(test.value).AssignableTo(typ.scanType) &&
+			(reflect.TypeOf(test.value).Kind() != typ.scanType.Kind() || !reflect.TypeOf(test.value).ConvertibleTo(typ.scanType)) {
+			t.Errorf("%v cannot be scanned into %v.", test.value, typ.scanType)
+		}
+	}
+}
+
+func TestStatementResult_ColumnTypes(t *testing.T) {
+	var (
+		wr bytes.Buffer
+		rd bytes.Buffer
+	)
+	c := testMockConn(t, &rd, &wr)
+	defer c.Close()
+	dst := make([]driver.Value, 2)
+
+	// The first record is read to infer the column types, then returned by Next.
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{"a", "b"}})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteRecord, []interface{}{int64(1), nil})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteRecord, []interface{}{int64(2), "b"})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	res, err := c.run(context.Background(), "RETURN 1", map[string]interface{}{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	if name := res.ColumnTypeDatabaseTypeName(0); name != "INTEGER" {
+		t.Errorf("invalid type name, expected %v got %v.", "INTEGER", name)
+	} else if name = res.ColumnTypeDatabaseTypeName(1); name != "ANY" {
+		t.Errorf("invalid type name of a null value, expected %v got %v.", "ANY", name)
+	} else if scanType := res.ColumnTypeScanType(0); scanType != reflect.TypeOf(int64(0)) {
+		t.Errorf("invalid scan type, expected int64 got %v.", scanType)
+	} else if nullable, ok := res.ColumnTypeNullable(0); !nullable || !ok {
+		t.Error("columns should be nullable.")
+	} else if err = res.Next(dst); err != nil {
+		t.Error(err)
+	} else if dst[0] != int64(1) {
+		t.Errorf("invalid first record, expected %v got %v.", 1, dst[0])
+	} else if err = res.Next(dst); err != nil {
+		t.Error(err)
+	} else if dst[0] != int64(2) {
+		t.Errorf("invalid second record, expected %v got %v.", 2, dst[0])
+	}
+}
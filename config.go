@@ -0,0 +1,279 @@
+package neoql
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the configuration of the connections opened by a connector, created with NewConnector. The options which
+// are not Go values, like the database or the access mode, are set by the connection string parameters.
+type Config struct {
+	// Target is the connection string, as passed to sql.Open. Its user information is ignored if Auth is set.
+	Target string
+	// Auth returns the authentication token of each new connection, so the credentials can be rotated without
+	// reopening the sql.DB. If nil, the credentials of the connection string are sent with the "basic" scheme.
+	Auth AuthProvider
+	// Dialer opens the network connections. If nil, a net.Dialer is used.
+	Dialer Dialer
+	// TLSConfig is the TLS configuration of the connections. If set, TLS is used whatever the connection string
+	// scheme, the server certificate not being verified with the "+ssc" schemes.
+	TLSConfig *tls.Config
+	// Logger logs the notifications which are not handled by a NotificationHandler, and the servers removed from the
+	// routing table of a cluster. If nil, nothing is logged.
+	Logger Logger
+	// ConnectTimeout bounds the time spent opening a connection: dialing, negotiating the protocol version and
+	// authenticating. If zero, only the deadline of the context applies.
+	ConnectTimeout time.Duration
+}
+
+// AuthToken is the authentication token sent to the server, "basic" being the scheme of a username and a password.
+type AuthToken struct {
+	Scheme      string
+	Principal   string
+	Credentials string
+}
+
+// AuthProvider returns the authentication token of a new connection.
+type AuthProvider func(ctx context.Context) (AuthToken, error)
+
+// Dialer opens network connections. It is implemented by net.Dialer.
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// Logger logs messages. It is implemented by log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// connector opens the connections configured by a Config. Its connection string is parsed once, when it is created.
+type connector struct {
+	driver    *neoDriver
+	config    Config
+	url       *url.URL
+	routed    bool                   // routed is true if the connections are routed within a cluster.
+	database  string                 // database selected by the connection string.
+	impUser   string                 // user impersonated by the connection string.
+	fetchSize int64                  // number of records pulled at once.
+	mode      string                 // access mode of the routed connections.
+	filters   map[string]interface{} // notification filters sent when initializing the connections.
+	style     string                 // style of the placeholders rewritten into Cypher parameters, if any.
+	legacy    string                 // mode of the legacy parameters rewriting.
+	explain   bool                   // explain is true if the prepared queries are validated with EXPLAIN.
+	bookmarks *BookmarkManager       // manager shared by the connections.
+}
+
+// newConnector returns a connector of the "d" driver, configured by "config", whose connections share the
+// "bookmarks" manager. It returns an error if the connection string is not valid.
+func (d *neoDriver) newConnector(config Config, bookmarks *BookmarkManager) (*connector, error) {
+	var err error
+
+	c := &connector{driver: d, config: config, bookmarks: bookmarks}
+	if c.url, err = url.Parse(config.Target); err != nil {
+		return nil, err
+	}
+	routed, ok := schemes[c.url.Scheme]
+	if !ok {
+		return nil, ErrBadScheme
+	}
+	c.routed = routed
+	query := c.url.Query()
+	c.database = query.Get("database")
+	if bookmarks != nil {
+		bookmarks.setDatabase(c.database)
+	}
+	c.impUser = query.Get("imp_user")
+	c.fetchSize = defaultFetchSize
+	if s := query.Get("fetch_size"); s != "" {
+		if c.fetchSize, err = strconv.ParseInt(s, 10, 64); err != nil || c.fetchSize == 0 || c.fetchSize < -1 {
+			return nil, errors.New("open: Invalid fetch_size, it must be a positive integer or -1")
+		}
+	}
+	c.mode = query.Get("mode")
+	if c.mode == "" {
+		c.mode = accessModeWrite
+	} else if c.mode != accessModeRead && c.mode != accessModeWrite {
+		return nil, errors.New("open: Invalid mode, it must be 'read' or 'write'")
+	}
+	if c.filters, err = notificationFilters(query); err != nil {
+		return nil, err
+	}
+	if c.style = query.Get("placeholders"); c.style != "" && !placeholderStyles[c.style] {
+		return nil, errors.New("open: Invalid placeholders, it must be 'question', 'dollar' or 'colon'")
+	}
+	if c.legacy = query.Get("legacy_params"); !legacyParamsModes[c.legacy] {
+		return nil, errors.New("open: Invalid legacy_params, it must be 'strict' or 'off'")
+	}
+	switch query.Get("prepare") {
+	case "":
+	case "explain":
+		c.explain = true
+	default:
+		return nil, errors.New("open: Invalid prepare, it must be 'explain'")
+	}
+	return c, nil
+}
+
+// Connect implements the Connect() method of the sql/driver.Connector interface.
+// With the "neo4j" URL schemes, it picks a server from the routing table of the cluster, according to the access
+// mode. Then, it sends the preamble to the Neo4j server, checks the supported versions, and initializes the connection
+// by authenticating to server.
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	var (
+		table   *routingTable
+		cn      *conn
+		err     error
+		address = c.url.Host
+	)
+
+	if c.routed {
+		if table, err = c.driver.routingTable(ctx, c); err != nil {
+			return nil, err
+		}
+		if address, err = table.server(c.mode); err != nil {
+			return nil, err
+		}
+	}
+	if cn, err = c.connect(ctx, address); err != nil {
+		if _, ok := err.(net.Error); ok && table != nil {
+			// The server is unreachable, another one is picked when retrying.
+			table.forget(address)
+			c.logf("neoql: %s removed from the routing table: %v", address, err)
+			return nil, driver.ErrBadConn
+		}
+		return nil, err
+	}
+	cn.database = c.database
+	cn.impUser = c.impUser
+	if err = cn.checkTarget(ctx); err != nil {
+		cn.Close()
+		return nil, err
+	}
+	cn.fetchSize = c.fetchSize
+	cn.bookmarks = c.bookmarks
+	cn.table = table
+	cn.address = address
+	cn.logger = c.config.Logger
+	cn.style = c.style
+	cn.legacy = c.legacy
+	cn.explain = c.explain
+	return cn, nil
+}
+
+// connect opens a network connection to the "address" server, using TLS according to the URL scheme and the TLS
+// configuration. Then, it negotiates the protocol version and authenticates, sending the notification filters. These
+// steps are interrupted once ctx is done, or once the connect timeout elapses.
+func (c *connector) connect(ctx context.Context, address string) (*conn, error) {
+	var (
+		netConn  net.Conn
+		bVersion [4]byte
+		version  uint32
+		err      error
+	)
+
+	if c.config.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.ConnectTimeout)
+		defer cancel()
+	}
+	token, err := c.authToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var dialer Dialer = &net.Dialer{}
+	if c.config.Dialer != nil {
+		dialer = c.config.Dialer
+	}
+	if netConn, err = dialer.DialContext(ctx, "tcp", address); err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		netConn.SetDeadline(deadline)
+	}
+	stop := watchConnect(ctx, netConn)
+	if config := c.tlsConfig(address); config != nil {
+		tlsConn := tls.Client(netConn, config)
+		err = tlsConn.Handshake()
+		netConn = tlsConn
+	}
+	if err == nil {
+		if _, err = netConn.Write(magicPreamble); err == nil {
+			_, err = netConn.Write(handshakeRequest[:])
+		}
+	}
+	if err == nil {
+		_, err = io.ReadFull(netConn, bVersion[:])
+	}
+	if err == nil {
+		if version = binary.BigEndian.Uint32(bVersion[:]); version == 0 || !isVersionSupported(version) {
+			err = ErrBadVersion
+		}
+	}
+	if err == nil && len(c.filters) != 0 && !versionAtLeast(version, 5, 2) {
+		err = ErrNotificationFilterUnsupported
+	}
+	var cn *conn
+	if err == nil {
+		cn, err = newConn(netConn, version, token.Scheme, token.Principal, token.Credentials, c.filters)
+	}
+	if ctxErr := stop(); ctxErr != nil {
+		err = ctxErr
+	}
+	if err == nil {
+		err = netConn.SetDeadline(time.Time{})
+	}
+	if err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	return cn, nil
+}
+
+// authToken returns the authentication token of a new connection: the one returned by the Auth provider, or else the
+// "basic" token made of the connection string credentials.
+func (c *connector) authToken(ctx context.Context) (AuthToken, error) {
+	if c.config.Auth != nil {
+		return c.config.Auth(ctx)
+	}
+	token := AuthToken{Scheme: "basic"}
+	if c.url.User != nil {
+		token.Principal = c.url.User.Username()
+		token.Credentials, _ = c.url.User.Password()
+	}
+	return token, nil
+}
+
+// tlsConfig returns the TLS configuration of a connection to the "address" server, or nil if TLS is not used.
+func (c *connector) tlsConfig(address string) *tls.Config {
+	selfSigned := strings.HasSuffix(c.url.Scheme, "+ssc")
+	if !strings.HasSuffix(c.url.Scheme, "+s") && !selfSigned && c.config.TLSConfig == nil {
+		return nil
+	}
+	config := new(tls.Config)
+	if c.config.TLSConfig != nil {
+		config = c.config.TLSConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName, _, _ = net.SplitHostPort(address)
+	}
+	if selfSigned {
+		config.InsecureSkipVerify = true
+	}
+	return config
+}
+
+// logf logs a message with the configured logger, if any.
+func (c *connector) logf(format string, v ...interface{}) {
+	if c.config.Logger != nil {
+		c.config.Logger.Printf(format, v...)
+	}
+}
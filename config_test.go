@@ -0,0 +1,143 @@
+package neoql
+
+import (
+	"context"
+	"crypto/tls"
+	"gopkg.in/neoql.v1/types"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// testDialer is a Dialer recording the addresses it dials.
+type testDialer struct {
+	addresses []string
+}
+
+// DialContext implements the Dialer interface.
+func (d *testDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d.addresses = append(d.addresses, address)
+	return (&net.Dialer{}).DialContext(ctx, network, address)
+}
+
+// testPipeDialer is a Dialer returning the client side of a pipe, whose server side is read by the test.
+type testPipeDialer struct {
+	client net.Conn
+}
+
+// DialContext implements the Dialer interface.
+func (d *testPipeDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return d.client, nil
+}
+
+func TestNeoDriver_newConnector(t *testing.T) {
+	c, err := (&neoDriver{}).newConnector(Config{Target: "neo4j+s://cluster:7687?database=sales&fetch_size=-1&mode=read&prepare=explain"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.routed || c.database != "sales" || c.fetchSize != -1 || c.mode != accessModeRead || !c.explain {
+		t.Errorf("invalid connector, got %+v.", c)
+	}
+
+	for _, target := range []string{
+		"http://localhost:7687",
+		"bolt://localhost:7687?fetch_size=0",
+		"bolt://localhost:7687?mode=any",
+		"bolt://localhost:7687?notifications_min_severity=loud",
+		"bolt://localhost:7687?prepare=plan",
+	} {
+		if _, err := (&neoDriver{}).newConnector(Config{Target: target}, nil); err == nil {
+			t.Errorf("error should not be nil for %v.", target)
+		}
+	}
+}
+
+func TestConnector_tlsConfig(t *testing.T) {
+	c, _ := (&neoDriver{}).newConnector(Config{Target: "bolt://localhost:7687"}, nil)
+	if config := c.tlsConfig("localhost:7687"); config != nil {
+		t.Errorf("TLS should not be used, got %v.", config)
+	}
+
+	c, _ = (&neoDriver{}).newConnector(Config{Target: "neo4j+ssc://cluster:7687"}, nil)
+	if config := c.tlsConfig("server1:7687"); config == nil {
+		t.Error("TLS should be used with the +ssc schemes.")
+	} else if !config.InsecureSkipVerify || config.ServerName != "server1" {
+		t.Errorf("invalid TLS configuration, got %+v.", config)
+	}
+
+	custom := &tls.Config{ServerName: "neo4j.example.com"}
+	c, _ = (&neoDriver{}).newConnector(Config{Target: "bolt://localhost:7687", TLSConfig: custom}, nil)
+	if config := c.tlsConfig("localhost:7687"); config == nil || config == custom {
+		t.Error("the TLS configuration should be a copy of the configured one.")
+	} else if config.InsecureSkipVerify || config.ServerName != "neo4j.example.com" {
+		t.Errorf("invalid TLS configuration, got %+v.", config)
+	}
+}
+
+func TestConnector_Connect(t *testing.T) {
+	dialer := new(testDialer)
+	var principals []string
+	config := Config{
+		Target: "bolt://0.0.0.0:7687",
+		Dialer: dialer,
+		Auth: func(ctx context.Context) (AuthToken, error) {
+			principals = append(principals, "neo4j")
+			return AuthToken{Scheme: "basic", Principal: "neo4j", Credentials: "toto"}, nil
+		},
+		ConnectTimeout: 5 * time.Second,
+	}
+	c, err := (&neoDriver{}).newConnector(config, new(BookmarkManager))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cn, err := c.Connect(context.Background()); err != nil {
+		t.Error(err)
+	} else {
+		cn.Close()
+		if len(dialer.addresses) != 1 || dialer.addresses[0] != "0.0.0.0:7687" {
+			t.Errorf("invalid dialed addresses, got %v.", dialer.addresses)
+		} else if len(principals) != 1 {
+			t.Errorf("the authentication token should be provided once, got %d times.", len(principals))
+		}
+	}
+
+	c.config.Auth = func(ctx context.Context) (AuthToken, error) {
+		return AuthToken{Scheme: "basic", Principal: "neo4j", Credentials: "invalid"}, nil
+	}
+	if _, err := c.Connect(context.Background()); err != types.ErrUnauthorized {
+		t.Errorf("error should be %v, got %v.", types.ErrUnauthorized, err)
+	}
+}
+
+func TestConnector_connect_canceled(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	c, err := (&neoDriver{}).newConnector(Config{Target: "bolt://0.0.0.0:7687", Dialer: &testPipeDialer{client}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The server reads the handshake but never answers, the connection is interrupted once ctx is canceled.
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		var handshake [20]byte
+		if _, err := io.ReadFull(server, handshake[:]); err != nil {
+			t.Error(err)
+		}
+		cancel()
+	}()
+	errs := make(chan error, 1)
+	go func() {
+		_, err := c.connect(ctx, "0.0.0.0:7687")
+		errs <- err
+	}()
+	select {
+	case err = <-errs:
+		if err != context.Canceled {
+			t.Errorf("error should be %v, got %v.", context.Canceled, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the connection should be interrupted once ctx is canceled.")
+	}
+}
@@ -1,100 +1,339 @@
 package neoql
 
 import (
+	"context"
+	"database/sql"
 	"database/sql/driver"
 	"gopkg.in/neoql.v1/types"
 	"gopkg.in/packstream.v1"
 	"io"
 	"net"
+	"runtime"
+	"strings"
+	"sync"
 )
 
 const (
-	byteInit       = 0x01 // Signature to initialize a connection
-	byteAckFailure = 0x0F // Signature to acknowledge a failure
+	byteInit       = 0x01 // Signature to initialize a connection (Bolt v1)
+	byteHello      = 0x01 // Signature to initialize a connection (Bolt v3+)
+	byteGoodbye    = 0x02 // Signature to close a connection (Bolt v3+)
+	byteLogon      = 0x6A // Signature to authenticate a connection (Bolt v5.1+)
+	byteLogoff     = 0x6B // Signature to log off a connection (Bolt v5.1+)
+	byteAckFailure = 0x0E // Signature to acknowledge a failure (Bolt v1)
+	byteReset      = 0x0F // Signature to reset a connection
 	byteRun        = 0x10 // Signature to run a query
-	byteDiscardAll = 0x2F // Unused
+	byteBegin      = 0x11 // Signature to begin a transaction (Bolt v3+)
+	byteCommit     = 0x12 // Signature to commit a transaction (Bolt v3+)
+	byteRollback   = 0x13 // Signature to rollback a transaction (Bolt v3+)
+	byteDiscardAll = 0x2F // Signature to discard all records resulting from a query
+	byteDiscard    = 0x2F // Signature to discard records resulting from a query (Bolt v4+)
 	bytePullAll    = 0x3F // Signature to pull all records resulting from a query
+	bytePull       = 0x3F // Signature to pull records resulting from a query (Bolt v4+)
+	byteRoute      = 0x66 // Signature to fetch a cluster routing table (Bolt v4.3+)
 	byteSuccess    = 0x70 // Signature to report a success
 	byteRecord     = 0x71 // Signature to report a record
 	byteIgnored    = 0x7E // Signature to report a response to be ignored
 	byteFailure    = 0x7F // Signature to report a failure
 )
 
+// userAgent is the user agent sent to the Neo4j server when initializing a connection.
+const userAgent = "Neo4jBoltDriver/1.0"
+
+// defaultFetchSize is the default number of records pulled at once on Bolt v4 and above.
+const defaultFetchSize = 1000
+
+// Reauthenticator is implemented by the neoql connections, so credentials can be changed on a live connection through
+// the Raw() method of a sql.Conn. Re-authentication requires Bolt v5.1, so Neo4j 5.5 or above.
+type Reauthenticator interface {
+	Reauthenticate(scheme, principal, credentials string) error
+}
+
+// ServerInfo is implemented by the neoql connections, so the server a connection is opened to can be described through
+// the Raw() method of a sql.Conn, for instance when logging a failed query.
+type ServerInfo interface {
+	// ServerAgent returns the agent of the server, like "Neo4j/5.13.0".
+	ServerAgent() string
+	// ProtocolVersion returns the version of the Bolt protocol agreed with the server.
+	ProtocolVersion() (major, minor int)
+	// ConnectionID returns the identifier of the connection on the server, like "bolt-42". It requires Bolt v3 or
+	// above, it is empty otherwise.
+	ConnectionID() string
+	// ServerHints returns the configuration hints sent by the server, like "connection.recv_timeout_seconds". It
+	// requires Bolt v4.3 or above, it is nil otherwise.
+	ServerHints() map[string]interface{}
+}
+
 // conn is the implementation of a Neo4j connection using the Bolt protocol.
 type conn struct {
-	wr       *Writer
-	rd       *Reader
-	conn     net.Conn
-	tx       *tx
-	badState bool
+	wr           *Writer
+	rd           *Reader
+	conn         net.Conn
+	tx           *tx
+	version      uint32
+	state        connState
+	database     string                 // database selected by the connection string.
+	impUser      string                 // user impersonated by the connection string.
+	inflight     []byte                 // signatures of the requests sent, whose summary response has not been read yet.
+	queued       int                    // number of the last inflight requests, still buffered in the writer.
+	stream       *statementResult       // result whose records are still streamed from the server.
+	fetchSize    int64                  // number of records pulled at once on Bolt v4 and above, -1 to pull all records.
+	bookmarks    *BookmarkManager       // manager shared by the connections opened with the same connection string.
+	sent         []string               // bookmarks sent with the current transaction.
+	sentDatabase string                 // database the bookmarks sent with the current transaction belong to.
+	table        *routingTable          // routing table of the cluster the server belongs to, nil if not routed.
+	address      string                 // address of the server.
+	filters      map[string]interface{} // notification filters sent when initializing the connection.
+	agent        string                 // agent of the server, returned when initializing the connection.
+	connID       string                 // identifier of the connection on the server.
+	hints        map[string]interface{} // configuration hints returned by the server.
+	logger       Logger                 // logger of the connector, nil if nothing is logged.
+	style        string                 // style of the placeholders rewritten into Cypher parameters, if any.
+	legacy       string                 // mode of the legacy parameters rewriting.
+	explain      bool                   // explain is true if the prepared queries are validated with EXPLAIN.
+
+	// mu guards the network writes and the interruption flags, since a request may be interrupted by the goroutine
+	// watching its context.
+	mu          sync.Mutex
+	interrupted bool // interrupted is true once the context of the current request is done.
+	resetSent   bool // resetSent is true if the current request has been interrupted with a RESET message.
 }
 
 // newConn returns a new connection, initializes its reader, writer, encoder, then attempts to authenticate to the
-// Neo4j database.
-func newConn(netConn net.Conn, scheme, principal, credentials string) (*conn, error) {
+// Neo4j database using the Bolt protocol "version" agreed during the handshake. The "filters" notification filters
+// are sent when initializing the connection, on Bolt v5.2 and above.
+func newConn(netConn net.Conn, version uint32, scheme, principal, credentials string, filters map[string]interface{}) (*conn, error) {
 	c := new(conn)
 	c.wr = NewWriter(netConn)
 	c.rd = NewReader(netConn)
 	c.conn = netConn
+	c.version = version
+	c.fetchSize = defaultFetchSize
+	c.filters = filters
 	if err := c.auth(scheme, principal, credentials); err != nil {
 		return nil, err
 	}
 	return c, nil
 }
 
-// writeMessage encodes a packstream structure, write it on the net.Conn then flush the chunk writer
-func (c *conn) writeMessage(v *packstream.Structure) (err error) {
-	var encoded []byte
+// writeMessage checks the message is allowed in the connection current state, encodes a packstream structure,
+// write it on the net.Conn then flush the chunk writer, along with the messages queued before.
+// It returns driver.ErrBadConn if the connection is defunct.
+func (c *conn) writeMessage(v *packstream.Structure) error {
+	if err := c.queueMessage(v); err != nil {
+		return err
+	}
+	return c.flush()
+}
+
+// queueMessage checks the message is allowed in the connection current state, encodes a packstream structure and
+// buffers it in the chunk writer, so several messages can be sent in a single flush.
+// It returns driver.ErrBadConn if the connection is defunct.
+func (c *conn) queueMessage(v *packstream.Structure) (err error) {
+	var (
+		encoded []byte
+		next    connState
+	)
 
+	if c.state == stateDefunct {
+		return driver.ErrBadConn
+	}
+	if next, err = c.nextState(v.Signature); err != nil {
+		return
+	}
 	if encoded, err = packstream.Marshal(v); err != nil {
 		return
 	}
 	if _, err = c.wr.Write(encoded); err != nil {
-		c.badState = true
+		c.broken()
 		return err
 	}
-	err = c.wr.Flush(true)
-	return err
+	c.wr.EndMessage()
+	c.state = next
+	if v.Signature != byteGoodbye {
+		c.inflight = append(c.inflight, v.Signature)
+		c.queued++
+	}
+	return nil
 }
 
-// readMessage reads a packstream structure, by decoding the incoming bytes on net.Conn.
-// If the structure signature is byteFailure, it acknowledge and returns the parsed error.
+// flush writes the queued messages on the net.Conn.
+// it returns driver.ErrBadConn if, and only if, writing failed with a io.EOF or io.ErrUnexpectedEOF
+func (c *conn) flush() error {
+	c.mu.Lock()
+	if c.interrupted {
+		// The queued requests must not reach the server after the RESET message, they are dropped.
+		c.mu.Unlock()
+		c.inflight = c.inflight[:len(c.inflight)-c.queued]
+		c.queued = 0
+		c.wr.discard()
+		return errInterrupted
+	}
+	err := c.wr.Flush(false)
+	c.mu.Unlock()
+	if err != nil {
+		c.broken()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return driver.ErrBadConn
+		}
+		return err
+	}
+	c.queued = 0
+	return nil
+}
+
+// readMessage reads a packstream structure, by decoding the incoming bytes on net.Conn, and updates the connection
+// state accordingly.
+// If the structure signature is byteFailure, it returns the parsed error. The failure is acknowledged later on, by
+// recoverFailure.
 func (c *conn) readMessage() (st *packstream.Structure, err error) {
 	var message []byte
 	if message, err = c.rd.ReadMessage(); err != nil {
-		c.badState = true
+		c.broken()
 		return
 	}
 	if err = packstream.Unmarshal(message, &st); err != nil {
+		c.state = stateDefunct
 		return nil, err
 	}
+	c.received(st)
 	if st.Signature == byteFailure {
-		c.ackFailure()
-		return nil, messageError(st, types.ErrProtocol)
+		err = messageError(st, types.ErrProtocol)
+		if cErr, ok := err.(*types.CypherError); ok && c.table != nil && isNotALeader(cErr.Code) {
+			// The server can no longer write, so the connection is dropped and the routing table fetched again.
+			c.table.forgetWriter(c.address)
+			c.state = stateDefunct
+		}
+		return nil, err
 	}
 	return
 }
 
-// request calls writeMessage then readMessage and returns the read message.
-// it returns driver.ErrBadConn if, and only if, it is writeMessage which failed with a io.EOF or io.ErrUnexpectedEOF
+// broken marks the connection as defunct once the network connection failed. If the connection is routed within a
+// cluster, the server is removed from the routing table, unless the failure comes from an interrupted request.
+func (c *conn) broken() {
+	c.mu.Lock()
+	interrupted := c.interrupted
+	c.mu.Unlock()
+	c.state = stateDefunct
+	if c.table != nil && !interrupted {
+		c.table.forget(c.address)
+		c.logf("neoql: %s removed from the routing table: the connection failed", c.address)
+	}
+}
+
+// recoverFailure must be called before running a new request. It returns driver.ErrBadConn if the connection is
+// defunct. Otherwise, it discards the responses still expected for the requests already flushed, then if a request
+// failed, it acknowledges the failure with ACK_FAILURE on Bolt v1, or RESET on Bolt v3 and above, which no longer
+// supports ACK_FAILURE.
+func (c *conn) recoverFailure() error {
+	// The responses to the queued requests cannot be read, they are read along the next request's response.
+	for len(c.inflight) > c.queued && c.state != stateDefunct {
+		c.readMessage()
+	}
+	switch c.state {
+	case stateDefunct:
+		return driver.ErrBadConn
+	case stateFailed:
+		signature := byte(byteAckFailure)
+		if c.atLeast(3, 0) {
+			signature = byteReset
+		}
+		if _, err := c.request(packstream.NewStructure(signature)); err != nil {
+			c.state = stateDefunct
+			return driver.ErrBadConn
+		}
+	}
+	return nil
+}
+
+// request calls writeMessage then reads the responses to the requests queued before, if any, and returns the
+// response to "v". If a queued request failed, its error is returned instead, "v" being ignored by the server.
 func (c *conn) request(v *packstream.Structure) (*packstream.Structure, error) {
+	pending := len(c.inflight)
 	if err := c.writeMessage(v); err != nil {
-		if err == io.EOF || err == io.ErrUnexpectedEOF {
-			return nil, driver.ErrBadConn
-		}
 		return nil, err
 	}
+	return c.response(pending)
+}
+
+// response reads the responses to the "pending" first requests sent, then returns the next response.
+// If one of the pending requests failed, its error is returned.
+func (c *conn) response(pending int) (*packstream.Structure, error) {
+	for ; pending > 0; pending-- {
+		if _, err := c.readMessage(); err != nil {
+			return nil, err
+		}
+	}
 	return c.readMessage()
 }
 
 // auth send a message on net.Conn to authenticate using scheme, principal and credentials.
+// On Bolt v1, it sends an INIT message, on Bolt v3 and above, it sends a HELLO message which carries the user agent
+// within the authentication token. Since Bolt v5.1, the HELLO message no longer carries the authentication token,
+// which is sent afterwards with a LOGON message. Since Bolt v5.2, it carries the notification filters.
+// The server agent, connection identifier and hints are read from the response.
 // It returns an error if authentication failed.
 func (c *conn) auth(scheme, principal, credentials string) error {
-	if res, err := c.request(packstream.NewStructure(byteInit, "Neo4jBoltDriver/1.0", map[string]interface{}{
-		"scheme":      scheme,
-		"principal":   principal,
-		"credentials": credentials,
-	})); err != nil {
+	var msg *packstream.Structure
+
+	token := authToken(scheme, principal, credentials)
+	if c.atLeast(5, 1) {
+		hello := map[string]interface{}{"user_agent": userAgent}
+		if c.atLeast(5, 3) {
+			hello["bolt_agent"] = boltAgent()
+		}
+		if c.atLeast(5, 2) {
+			for k, v := range c.filters {
+				hello[k] = v
+			}
+		}
+		msg = packstream.NewStructure(byteHello, hello)
+	} else if c.atLeast(3, 0) {
+		token["user_agent"] = userAgent
+		msg = packstream.NewStructure(byteHello, token)
+	} else {
+		msg = packstream.NewStructure(byteInit, userAgent, token)
+	}
+	if res, err := c.request(msg); err != nil {
+		return err
+	} else if res.Signature != byteSuccess {
+		return messageError(res, types.ErrProtocol)
+	} else if len(res.Fields) != 0 {
+		if metadata, ok := res.Fields[0].(map[string]interface{}); ok {
+			c.agent, _ = metadata["server"].(string)
+			c.connID, _ = metadata["connection_id"].(string)
+			c.hints, _ = metadata["hints"].(map[string]interface{})
+		}
+	}
+	if c.atLeast(5, 1) {
+		return c.logon(token)
+	}
+	return nil
+}
+
+// ServerAgent implements the ServerInfo interface.
+func (c *conn) ServerAgent() string {
+	return c.agent
+}
+
+// ProtocolVersion implements the ServerInfo interface.
+func (c *conn) ProtocolVersion() (major, minor int) {
+	return int(versionMajor(c.version)), int(versionMinor(c.version))
+}
+
+// ConnectionID implements the ServerInfo interface.
+func (c *conn) ConnectionID() string {
+	return c.connID
+}
+
+// ServerHints implements the ServerInfo interface.
+func (c *conn) ServerHints() map[string]interface{} {
+	return c.hints
+}
+
+// logon sends a LOGON message with the authentication token on Bolt v5.1 and above.
+func (c *conn) logon(token map[string]interface{}) error {
+	if res, err := c.request(packstream.NewStructure(byteLogon, token)); err != nil {
 		return err
 	} else if res.Signature != byteSuccess {
 		return messageError(res, types.ErrProtocol)
@@ -102,55 +341,303 @@ func (c *conn) auth(scheme, principal, credentials string) error {
 	return nil
 }
 
+// Reauthenticate implements the Reauthenticator interface.
+// It sends a LOGOFF message, then a LOGON message with the new authentication token. It requires Bolt v5.1 or above.
+func (c *conn) Reauthenticate(scheme, principal, credentials string) error {
+	if !c.atLeast(5, 1) {
+		return ErrReauthUnsupported
+	}
+	if c.tx != nil {
+		return ErrTransactionStarted
+	}
+	if err := c.ready(); err != nil {
+		return err
+	}
+	if res, err := c.request(packstream.NewStructure(byteLogoff)); err != nil {
+		return err
+	} else if res.Signature != byteSuccess {
+		return messageError(res, types.ErrProtocol)
+	}
+	return c.logon(authToken(scheme, principal, credentials))
+}
+
+// authToken returns the authentication token sent to the server.
+func authToken(scheme, principal, credentials string) map[string]interface{} {
+	return map[string]interface{}{
+		"scheme":      scheme,
+		"principal":   principal,
+		"credentials": credentials,
+	}
+}
+
+// boltAgent returns the driver information sent in the HELLO message since Bolt v5.3.
+func boltAgent() map[string]interface{} {
+	return map[string]interface{}{
+		"product":  userAgent,
+		"platform": runtime.GOOS + "; " + runtime.GOARCH,
+		"language": "Go/" + runtime.Version(),
+	}
+}
+
 // Begin implements the Begin() method of the sql/driver.Conn interface.
-// It runs a BEGIN Cypher query.
 func (c *conn) Begin() (driver.Tx, error) {
-	if c.badState {
-		return nil, driver.ErrBadConn
-	}
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
 
+// BeginTx implements the BeginTx() method of the sql/driver.ConnBeginTx interface.
+// On Bolt v3 and above, it sends a BEGIN message, carrying the bookmarks to wait for, and the database, impersonated
+// user, transaction metadata, timeout and additional bookmarks set in ctx, if any. A read-only transaction is sent
+// with the read access mode, so a cluster can run it on a follower. On Bolt v1, it runs a BEGIN Cypher query.
+// Neo4j transactions are read committed, so only the default and the read committed isolation levels are accepted.
+// The message is not sent right away, but along with the first query of the transaction, so it does not cost an
+// additional round trip. Hence, a failure to begin the transaction is returned by this query.
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
 	if c.tx != nil {
 		return nil, ErrTransactionStarted
 	}
-	if _, err := c.run("BEGIN", map[string]interface{}{}); err != nil {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := c.checkTarget(ctx); err != nil {
+		return nil, err
+	}
+	switch sql.IsolationLevel(opts.Isolation) {
+	case sql.LevelDefault, sql.LevelReadCommitted:
+	default:
+		return nil, ErrIsolationUnsupported
+	}
+	config := txConfig(ctx)
+	if opts.ReadOnly {
+		config["mode"] = "r"
+	}
+	if err := c.ready(); err != nil {
+		return nil, err
+	}
+	if c.atLeast(3, 0) {
+		if err := c.queueMessage(c.beginMessage(ctx, config)); err != nil {
+			return nil, err
+		}
+	} else if opts.ReadOnly {
+		return nil, ErrReadOnlyUnsupported
+	} else if len(config) != 0 {
+		return nil, ErrTxConfigUnsupported
+	} else if err := c.queueMessage(c.runMessage(ctx, "BEGIN", map[string]interface{}{})); err != nil {
+		return nil, err
+	} else if err := c.queueMessage(c.discardMessage(-1, -1)); err != nil {
 		return nil, err
 	}
 	c.tx = &tx{conn: c}
 	return c.tx, nil
 }
 
+// QueryContext implements the QueryContext() method of the sql/driver.QueryerContext interface.
+// The database and the impersonated user can be selected with WithDatabase and WithImpersonatedUser. With WithScript,
+// the query is a script whose statements return a result set each.
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	params, err := namedValuesToParams(args)
+	if err != nil {
+		return nil, err
+	}
+	if query, err = c.rewrite(query); err != nil {
+		return nil, err
+	}
+	if statements := scriptStatements(ctx, query); len(statements) > 1 {
+		return c.runScript(ctx, statements, params)
+	}
+	return c.run(ctx, query, params, false)
+}
+
+// ExecContext implements the ExecContext() method of the sql/driver.ExecerContext interface.
+// The database and the impersonated user can be selected with WithDatabase and WithImpersonatedUser. The records
+// returned by the query, if any, are discarded by the server. The returned Result carries the update counters, summed
+// over the statements of a script with WithScript.
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	params, err := namedValuesToParams(args)
+	if err != nil {
+		return nil, err
+	}
+	if query, err = c.rewrite(query); err != nil {
+		return nil, err
+	}
+	if statements := scriptStatements(ctx, query); len(statements) > 1 {
+		counters, err := c.execScript(ctx, statements, params)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Counters: counters}, nil
+	}
+	res, err := c.exec(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Counters: res.Counters}, nil
+}
+
+// CheckNamedValue implements the CheckNamedValue() method of the sql/driver.NamedValueChecker interface.
+// It accepts the maps with string keys, the slices and the arrays, whose items are converted recursively, so they are
+// sent as Cypher maps and lists.
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) (err error) {
+	nv.Value, err = convertValue(nv.Value)
+	return err
+}
+
 // Close implements the Close() method of the sql/driver.Conn interface.
+// On Bolt v3 and above, it sends a GOODBYE message before closing the network connection.
 func (c *conn) Close() error {
+	if c.stream != nil && c.stream.stop != nil {
+		c.stream.stop()
+	}
+	if c.atLeast(3, 0) && c.state != stateDefunct {
+		// The server does not reply to a GOODBYE message, and the connection is closed anyway.
+		c.writeMessage(packstream.NewStructure(byteGoodbye))
+	}
 	c.wr = nil
 	c.rd = nil
 	c.tx = nil
 	return c.conn.Close()
 }
 
-// Prepare implements the Prepare() method of the sql/driver.Conn interface.
-func (c *conn) Prepare(query string) (driver.Stmt, error) {
-	if c.badState {
+// Ping implements the Ping() method of the sql/driver.Pinger interface.
+// On Bolt v3 and above, it sends a RESET message, which the server replies to without running any query. Within a
+// transaction, which RESET would roll back, and on Bolt v1, it runs a "RETURN 1" query instead. It returns
+// driver.ErrBadConn if the connection is defunct, so the sql.DB drops it.
+func (c *conn) Ping(ctx context.Context) (err error) {
+	if c.tx != nil || !c.atLeast(3, 0) {
+		_, err = c.exec(ctx, "RETURN 1", map[string]interface{}{})
+	} else {
+		err = c.reset(ctx)
+	}
+	if err != nil && err != ctx.Err() && c.state == stateDefunct {
+		return driver.ErrBadConn
+	}
+	return err
+}
+
+// ResetSession implements the ResetSession() method of the sql/driver.SessionResetter interface.
+// It is called before a pooled connection is reused: the records still streamed are discarded, a transaction left
+// open is rolled back, and a failure is acknowledged, so the connection is ready. It returns driver.ErrBadConn if the
+// connection cannot be made ready, so the sql.DB drops it.
+func (c *conn) ResetSession(ctx context.Context) error {
+	if c.stream != nil {
+		c.stream.Close()
+	}
+	if c.tx != nil {
+		c.tx.Rollback()
+	}
+	if err := c.recoverFailure(); err != nil || c.state != stateReady {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+// IsValid implements the IsValid() method of the sql/driver.Validator interface.
+// It returns false once the connection is defunct, so the sql.DB does not put it back into the pool.
+func (c *conn) IsValid() bool {
+	return c.state != stateDefunct
+}
+
+// PrepareContext implements the PrepareContext() method of the sql/driver.ConnPrepareContext interface.
+// Nothing is sent to the server, the query is sent when the statement is executed, unless the connection string sets
+// "prepare=explain": the query is then run with EXPLAIN, so an invalid query fails right away, and the statement
+// expects as many arguments as the query references parameters. The scripts are not explained.
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if c.state == stateDefunct {
 		return nil, driver.ErrBadConn
 	}
-	return &stmt{conn: c, query: query}, nil
+	stm := &stmt{conn: c, query: query}
+	if !c.explain || len(scriptStatements(ctx, query)) > 1 {
+		return stm, nil
+	}
+	rewritten, err := c.rewrite(query)
+	if err != nil {
+		return nil, err
+	}
+	// The parameters are not sent along EXPLAIN, so the notifications of the missing parameters are dropped.
+	handler := c.notificationHandler(ctx)
+	explainCtx := WithNotificationHandler(ctx, func(n Notification) {
+		if handler != nil && !strings.HasSuffix(n.Code, parameterNotProvided) {
+			handler(n)
+		}
+	})
+	res, err := c.exec(explainCtx, "EXPLAIN "+rewritten, map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+	stm.explained = true
+	stm.columns = res.Fields
+	stm.numInput = numParameters(rewritten)
+	return stm, nil
+}
+
+// Prepare implements the Prepare() method of the sql/driver.Conn interface.
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
 }
 
-// run runs the "statement" Cypher query with the "params" parameters.
-// Then, it pulls all records, fills the statement summary, and returns them through a statementResult.
-func (c *conn) run(statement string, params map[string]interface{}) (*statementResult, error) {
+// run runs the "statement" Cypher query with the "params" parameters, and requests its records in the same flush.
+// Then, it returns a statementResult which reads them from the connection on demand. The records are pulled by
+// batches of fetchSize records on Bolt v4 and above. If "discard" is true, the records are discarded by the server
+// instead, and only the summary is read.
+// If the server responses are not valid, the connection is considered defunct. If ctx is done before the end of the
+// stream, the query is interrupted and the ctx error is returned.
+func (c *conn) run(ctx context.Context, statement string, params map[string]interface{}, discard bool) (_ *statementResult, err error) {
 	var (
-		field   string
-		records []interface{}
-		record  interface{}
-		convOK  bool
+		field  string
+		qid    int64
+		convOK bool
 	)
 
-	if c.badState {
-		return nil, driver.ErrBadConn
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err = c.ready(); err != nil {
+		return nil, err
+	}
+	if c.tx != nil && c.atLeast(3, 0) && c.state != stateTxReady {
+		// The transaction has been rolled back while recovering from a failure.
+		return nil, ErrTransactionFailed
 	}
+	if c.tx == nil {
+		if err = c.checkTarget(ctx); err != nil {
+			return nil, err
+		}
+	}
+	defer func() {
+		if err == types.ErrProtocol {
+			c.state = stateDefunct
+		}
+	}()
 
-	result := new(statementResult)
-	if res, err := c.request(packstream.NewStructure(byteRun, statement, params)); err != nil {
+	// The records are requested along with the query, so both messages are sent in a single flush. If the query
+	// fails, the server ignores the second message. The query id being unknown yet, it targets the last query run.
+	next := c.pullMessage(c.fetchSize, -1)
+	if discard {
+		next = c.discardMessage(-1, -1)
+	}
+	pending := len(c.inflight)
+	if err = c.queueMessage(c.runMessage(ctx, statement, params)); err != nil {
+		return nil, err
+	} else if err = c.queueMessage(next); err != nil {
+		return nil, err
+	} else if err = c.flush(); err != nil {
+		return nil, err
+	}
+	// The responses are read while watching ctx, until the end of the stream.
+	stop := c.watch(ctx)
+	defer func() {
+		if err != nil {
+			if ctxErr := stop(); ctxErr != nil {
+				err = ctxErr
+			}
+		}
+	}()
+
+	result := &statementResult{conn: c, qid: -1, autocommit: c.tx == nil, stop: stop}
+	result.handler = c.notificationHandler(ctx)
+	if res, err := c.response(pending); err != nil {
 		return nil, err
 	} else if res.Signature != byteSuccess {
 		return nil, messageError(res, types.ErrProtocol)
@@ -170,68 +657,214 @@ func (c *conn) run(statement string, params map[string]interface{}) (*statementR
 			}
 			result.Fields[i] = field
 		}
+		if qid, convOK = m["qid"].(int64); convOK {
+			result.qid = qid
+		}
 	}
 
-	if err := c.writeMessage(packstream.NewStructure(bytePullAll)); err != nil {
+	result.streaming = true
+	c.stream = result
+	return result, nil
+}
+
+// exec runs the "statement" Cypher query with the "params" parameters, asks the server to discard its records, and
+// returns the statementResult once its summary is read.
+func (c *conn) exec(ctx context.Context, statement string, params map[string]interface{}) (*statementResult, error) {
+	result, err := c.run(ctx, statement, params, true)
+	if err != nil {
 		return nil, err
 	}
+	if _, err = result.fetch(true); err != io.EOF {
+		return nil, err
+	}
+	return result, nil
+}
 
-	result.Rows = make(rows, 0)
-	i := 0
-	for {
-		if res, err := c.readMessage(); err != nil {
-			return nil, err
-		} else if res.Signature != byteSuccess && res.Signature != byteRecord {
-			return nil, messageError(res, types.ErrProtocol)
-		} else if len(res.Fields) == 0 {
-			return nil, types.ErrProtocol
-		} else if res.Signature == byteRecord {
-			if records, convOK = res.Fields[0].([]interface{}); !convOK {
-				return nil, types.ErrProtocol
-			}
-			if len(result.Fields) != len(records) {
-				return nil, types.ErrProtocol
-			}
-			result.Rows = append(result.Rows, make(map[string]interface{}))
-			j := 0
-			for _, item := range records {
-				if record, err = recordToType(item); err != nil {
-					return nil, err
-				}
-				result.Rows[i][result.Fields[j]] = record
-				j++
-			}
-			i++
-		} else if res.Signature == byteSuccess {
-			if err := result.hydrateSummary(res); err != nil {
-				return nil, err
-			}
-			break
-		} else {
-			return nil, messageError(res, types.ErrProtocol)
+// reset sends a RESET message and reads its response, so the connection is ready. If ctx is done before the response
+// is read, the ctx error is returned.
+func (c *conn) reset(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := c.ready(); err != nil {
+		return err
+	}
+	stop := c.watch(ctx)
+	res, err := c.request(packstream.NewStructure(byteReset))
+	if ctxErr := stop(); ctxErr != nil {
+		return ctxErr
+	} else if err != nil {
+		return err
+	} else if res.Signature != byteSuccess {
+		return messageError(res, types.ErrProtocol)
+	}
+	return nil
+}
+
+// ready must be called before sending a new request: the records of the result still streamed, if any, are read
+// and kept in memory so the responses to the new request can be read. Then, it recovers the connection if a
+// previous request failed.
+func (c *conn) ready() error {
+	if c.stream != nil {
+		c.stream.buffer()
+	}
+	return c.recoverFailure()
+}
+
+// rewrite returns the "query" Cypher query whose placeholders are rewritten into Cypher parameters, according to the
+// placeholder style of the connection string, if any. On Bolt v4 and above, the legacy "{name}" parameters, no longer
+// supported by the server, are rewritten into "$name" parameters, unless disabled by the connection string.
+func (c *conn) rewrite(query string) (_ string, err error) {
+	if c.style != "" {
+		if query, err = rewritePlaceholders(query, c.style); err != nil {
+			return "", err
 		}
 	}
-	return result, nil
+	if c.atLeast(4, 0) && c.legacy != legacyParamsOff && strings.IndexByte(query, '{') >= 0 {
+		return rewriteLegacyParams(query, c.legacy == legacyParamsStrict)
+	}
+	return query, nil
 }
 
-// ackFailure acknowledges a failure by sending the corresponding message.
-// Then, it reads all messages until a "Success" message is received, and discards the "Ignored" messages.
-func (c *conn) ackFailure() (err error) {
-	var res *packstream.Structure
+// logf logs a message with the logger of the connector, if any.
+func (c *conn) logf(format string, v ...interface{}) {
+	if c.logger != nil {
+		c.logger.Printf(format, v...)
+	}
+}
 
-	if err = c.writeMessage(packstream.NewStructure(byteAckFailure)); err != nil {
-		return
+// notificationHandler returns the handler of the notifications of a query: the NotificationHandler of ctx, or else
+// logNotification if the connection has a logger. It is nil if the notifications are not handled.
+func (c *conn) notificationHandler(ctx context.Context) NotificationHandler {
+	if handler, _ := ctx.Value(notificationHandlerKey).(NotificationHandler); handler != nil {
+		return handler
+	} else if c.logger != nil {
+		return c.logNotification
 	}
-	for {
-		if res, err = c.readMessage(); err != nil {
-			return
+	return nil
+}
+
+// logNotification logs a notification returned by the server, when it is not handled by a NotificationHandler.
+func (c *conn) logNotification(n Notification) {
+	c.logf("neoql: %s %s: %s", n.Severity, n.Code, n.Description)
+}
+
+// atLeast returns true if the protocol version agreed with the server is greater than or equal to major.minor.
+func (c *conn) atLeast(major, minor uint32) bool {
+	return versionAtLeast(c.version, major, minor)
+}
+
+// runMessage returns the RUN message for the "statement" Cypher query with the "params" parameters.
+// Bolt v3 and above expects an additional map of metadata. When the query runs outside of a transaction, it carries
+// the database, the impersonated user and the bookmarks to wait for, otherwise these are set by the BEGIN message.
+func (c *conn) runMessage(ctx context.Context, statement string, params map[string]interface{}) *packstream.Structure {
+	if c.atLeast(3, 0) {
+		extra := map[string]interface{}{}
+		if c.tx == nil {
+			extra = c.extra(ctx)
+			c.sendBookmarks(extra, nil)
 		}
-		if res.Signature != byteIgnored {
-			break
+		return packstream.NewStructure(byteRun, statement, params, extra)
+	}
+	return packstream.NewStructure(byteRun, statement, params)
+}
+
+// beginMessage returns the BEGIN message of a transaction on Bolt v3 and above, "config" being the transaction
+// metadata, timeout and access mode, and the bookmarks to wait for being the manager ones and the ctx ones.
+func (c *conn) beginMessage(ctx context.Context, config map[string]interface{}) *packstream.Structure {
+	extra := c.extra(ctx)
+	for k, v := range config {
+		extra[k] = v
+	}
+	c.sendBookmarks(extra, contextBookmarks(ctx))
+	return packstream.NewStructure(byteBegin, extra)
+}
+
+// pullMessage returns the message to pull "n" records of the "qid" query result, n being -1 to pull all records
+// and qid being -1 for the last query run.
+// Before Bolt v4, it is a PULL_ALL message which does not take any argument.
+func (c *conn) pullMessage(n, qid int64) *packstream.Structure {
+	if c.atLeast(4, 0) {
+		return packstream.NewStructure(bytePull, streamExtra(n, qid))
+	}
+	return packstream.NewStructure(bytePullAll)
+}
+
+// discardMessage returns the message to discard "n" records of the "qid" query result, n being -1 to discard all
+// records and qid being -1 for the last query run.
+// Before Bolt v4, it is a DISCARD_ALL message which does not take any argument.
+func (c *conn) discardMessage(n, qid int64) *packstream.Structure {
+	if c.atLeast(4, 0) {
+		return packstream.NewStructure(byteDiscard, streamExtra(n, qid))
+	}
+	return packstream.NewStructure(byteDiscardAll)
+}
+
+// extra returns the metadata map sent along BEGIN messages, and RUN messages outside of a transaction, on Bolt v3 and
+// above. It carries the database on Bolt v4 and above, the impersonated user on Bolt v4.4 and above, and the ctx
+// notification filters on Bolt v5.2 and above.
+func (c *conn) extra(ctx context.Context) map[string]interface{} {
+	extra := map[string]interface{}{}
+	if database := c.targetDatabase(ctx); database != "" && c.atLeast(4, 0) {
+		extra["db"] = database
+	}
+	if user := c.targetUser(ctx); user != "" && c.atLeast(4, 4) {
+		extra["imp_user"] = user
+	}
+	if c.atLeast(5, 2) {
+		filters, _ := contextNotificationFilters(ctx)
+		for k, v := range filters {
+			extra[k] = v
 		}
 	}
-	if res.Signature != byteSuccess {
-		err = messageError(res, types.ErrProtocol)
+	return extra
+}
+
+// targetDatabase returns the database the queries run against: the one set with WithDatabase in ctx, or else the
+// connection string one. The server default database is used when empty.
+func (c *conn) targetDatabase(ctx context.Context) string {
+	if database, ok := ctx.Value(databaseKey).(string); ok {
+		return database
+	}
+	return c.database
+}
+
+// targetUser returns the user the queries run as: the one set with WithImpersonatedUser in ctx, or else the
+// connection string one. The authenticated user is used when empty.
+func (c *conn) targetUser(ctx context.Context) string {
+	if user, ok := ctx.Value(impersonatedUserKey).(string); ok {
+		return user
 	}
-	return
+	return c.impUser
+}
+
+// checkTarget returns an error if a database or an impersonated user is selected, or notifications are filtered by
+// ctx, and the protocol version does not support it. On a routed connection, ctx cannot select another database than
+// the connection string one, since the server has been picked from the routing table of this database.
+func (c *conn) checkTarget(ctx context.Context) error {
+	if c.targetDatabase(ctx) != "" && !c.atLeast(4, 0) {
+		return ErrDatabaseUnsupported
+	}
+	if c.table != nil && c.targetDatabase(ctx) != c.database {
+		return ErrRoutedDatabase
+	}
+	if c.targetUser(ctx) != "" && !c.atLeast(4, 4) {
+		return ErrImpersonationUnsupported
+	}
+	if filters, err := contextNotificationFilters(ctx); err != nil {
+		return err
+	} else if filters != nil && !c.atLeast(5, 2) {
+		return ErrNotificationFilterUnsupported
+	}
+	return nil
+}
+
+// streamExtra returns the metadata map of PULL and DISCARD messages. The "qid" key is omitted when it targets the
+// last query run.
+func streamExtra(n, qid int64) map[string]interface{} {
+	extra := map[string]interface{}{"n": n}
+	if qid != -1 {
+		extra["qid"] = qid
+	}
+	return extra
 }
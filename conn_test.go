@@ -2,9 +2,15 @@ package neoql
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
 	"gopkg.in/neoql.v1/types"
 	"gopkg.in/packstream.v1"
+	"io"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -23,10 +29,12 @@ func testMakeConn(t *testing.T) *conn {
 
 // testMockConn returns the sql/driver.Conn implementation to run tests against it, and replaces the connection
 // writer and reader with "rd" and "wr" for testing purposes.
+// The connection protocol version is set to Bolt v1, whatever the version agreed with the server.
 func testMockConn(t *testing.T, rd *bytes.Buffer, wr *bytes.Buffer) *conn {
 	c := testMakeConn(t)
 	c.rd.rd = rd
 	c.wr.wr = wr
+	c.version = 1
 	return c
 }
 
@@ -45,6 +53,42 @@ func testGetEncodedMessage(t *testing.T, v interface{}) []byte {
 	return b.Bytes()
 }
 
+// testDecodeMessage returns the packstream structure of the first message sent through the chunk writer, for testing
+// purposes.
+func testDecodeMessage(t *testing.T, data []byte) *packstream.Structure {
+	var st *packstream.Structure
+	if message, err := NewReader(bytes.NewReader(data)).ReadMessage(); err != nil {
+		t.Fatal(err)
+	} else if err = packstream.Unmarshal(message, &st); err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+// testRunAll runs a Cypher query on the connection and reads all its records, for testing purposes.
+func testRunAll(c *conn, statement string, params map[string]interface{}) (rows, error) {
+	res, err := c.run(context.Background(), statement, params, false)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	records := make(rows, 0)
+	dest := make([]driver.Value, len(res.Columns()))
+	for {
+		if err = res.Next(dest); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{})
+		for i, f := range res.Columns() {
+			row[f] = dest[i]
+		}
+		records = append(records, row)
+	}
+}
+
 func TestNewConn(t *testing.T) {
 	b := new(bytes.Buffer)
 	c := testMockConn(t, b, b)
@@ -62,12 +106,28 @@ func TestConn_writeMessage(t *testing.T) {
 	c := testMockConn(t, &b, &b)
 	defer c.Close()
 
-	decoded := packstream.Structure{Signature: 42, Fields: []interface{}{"hello", []interface{}{int64(55)}}}
+	decoded := packstream.Structure{Signature: byteRun, Fields: []interface{}{"hello", []interface{}{int64(55)}}}
 	encoded := testGetEncodedMessage(t, decoded)
 	if err := c.writeMessage(&decoded); err != nil {
 		t.Error(err)
 	} else if !bytes.Equal(b.Bytes(), encoded) {
 		t.Errorf("written data are not valid, expected %# X got %# X.", b.Bytes(), encoded)
+	} else if c.state != stateStreaming {
+		t.Errorf("connection state should be %v, got %v.", stateStreaming, c.state)
+	} else if len(c.inflight) != 1 {
+		t.Errorf("connection should wait for %v response, got %v.", 1, len(c.inflight))
+	}
+
+	b.Reset()
+	if err := c.writeMessage(&decoded); err == nil {
+		t.Error("error should not be nil when message is not allowed in the connection state.")
+	} else if b.Len() != 0 {
+		t.Errorf("no data should be written when message is not allowed, got %# X.", b.Bytes())
+	}
+
+	c.state = stateDefunct
+	if err := c.writeMessage(packstream.NewStructure(byteReset)); err != driver.ErrBadConn {
+		t.Errorf("error should be %v when connection is defunct, got %v.", driver.ErrBadConn, err)
 	}
 }
 
@@ -90,8 +150,8 @@ func TestConn_readMessage(t *testing.T) {
 	b.Reset()
 	if _, err := c.readMessage(); err == nil {
 		t.Error("error should not be nil when attempting to read from an empty buffer")
-	} else if !c.badState {
-		t.Error("connection should be in bad state when attempting to read from an empty buffer")
+	} else if c.state != stateDefunct {
+		t.Error("connection should be defunct when attempting to read from an empty buffer")
 	}
 	c.wr.Write([]byte{0xB2})
 	if _, err := c.readMessage(); err == nil {
@@ -104,7 +164,7 @@ func TestConn_request(t *testing.T) {
 	c := testMockConn(t, &b, &b)
 	defer c.Close()
 
-	decoded := packstream.Structure{Signature: 42, Fields: []interface{}{"hello", []interface{}{int64(55)}}}
+	decoded := packstream.Structure{Signature: byteRun, Fields: []interface{}{"hello", []interface{}{int64(55)}}}
 	if st, err := c.request(&decoded); err != nil {
 		t.Error(err)
 	} else if !reflect.DeepEqual(st, &decoded) {
@@ -121,6 +181,7 @@ func TestConn_auth(t *testing.T) {
 	c := testMockConn(t, &rd, &wr)
 	defer c.Close()
 
+	c.state = stateConnected
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess)))
 	data := testGetEncodedMessage(t, packstream.NewStructure(byteInit, "Neo4jBoltDriver/1.0", map[string]interface{}{
 		"scheme":      "a",
@@ -132,6 +193,131 @@ func TestConn_auth(t *testing.T) {
 	} else if len(data) != len(wr.Bytes()) {
 		t.Errorf("Unexpected authorization message, expected length of %# x got %# x.", len(data), len(wr.Bytes()))
 	}
+
+	// Bolt v3 HELLO
+	wr.Reset()
+	c.version = 3
+	c.state = stateConnected
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	data = testGetEncodedMessage(t, packstream.NewStructure(byteHello, map[string]interface{}{
+		"user_agent":  "Neo4jBoltDriver/1.0",
+		"scheme":      "a",
+		"principal":   "b",
+		"credentials": "c",
+	}))
+	if err := c.auth("a", "b", "c"); err != nil {
+		t.Error(err)
+	} else if len(data) != len(wr.Bytes()) {
+		t.Errorf("Unexpected hello message, expected length of %# x got %# x.", len(data), len(wr.Bytes()))
+	}
+
+	// Bolt v5.1 HELLO then LOGON
+	wr.Reset()
+	c.version = 0x0105
+	c.state = stateConnected
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	data = testGetEncodedMessage(t, packstream.NewStructure(byteHello, map[string]interface{}{
+		"user_agent": "Neo4jBoltDriver/1.0",
+	}))
+	data = append(data, testGetEncodedMessage(t, packstream.NewStructure(byteLogon, map[string]interface{}{
+		"scheme":      "a",
+		"principal":   "b",
+		"credentials": "c",
+	}))...)
+	if err := c.auth("a", "b", "c"); err != nil {
+		t.Error(err)
+	} else if len(data) != len(wr.Bytes()) {
+		t.Errorf("Unexpected hello and logon messages, expected length of %# x got %# x.", len(data), len(wr.Bytes()))
+	}
+
+	// Bolt v5.2 HELLO with notification filters
+	wr.Reset()
+	c.version = 0x0205
+	c.state = stateConnected
+	c.filters = map[string]interface{}{"notifications_minimum_severity": "WARNING"}
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	data = testGetEncodedMessage(t, packstream.NewStructure(byteHello, map[string]interface{}{
+		"user_agent":                     "Neo4jBoltDriver/1.0",
+		"notifications_minimum_severity": "WARNING",
+	}))
+	data = append(data, testGetEncodedMessage(t, packstream.NewStructure(byteLogon, map[string]interface{}{
+		"scheme":      "a",
+		"principal":   "b",
+		"credentials": "c",
+	}))...)
+	if err := c.auth("a", "b", "c"); err != nil {
+		t.Error(err)
+	} else if len(data) != len(wr.Bytes()) {
+		t.Errorf("Unexpected hello and logon messages, expected length of %# x got %# x.", len(data), len(wr.Bytes()))
+	}
+}
+
+func TestConn_ServerInfo(t *testing.T) {
+	var (
+		wr bytes.Buffer
+		rd bytes.Buffer
+	)
+	c := testMockConn(t, &rd, &wr)
+	defer c.Close()
+
+	c.version = 0x0304
+	c.state = stateConnected
+	hints := map[string]interface{}{"connection.recv_timeout_seconds": int64(120)}
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{
+		"server":        "Neo4j/4.3.0",
+		"connection_id": "bolt-42",
+		"hints":         hints,
+	})))
+	var info ServerInfo = c
+	if err := c.auth("a", "b", "c"); err != nil {
+		t.Error(err)
+	} else if agent := info.ServerAgent(); agent != "Neo4j/4.3.0" {
+		t.Errorf("invalid server agent, expected %v got %v.", "Neo4j/4.3.0", agent)
+	} else if major, minor := info.ProtocolVersion(); major != 4 || minor != 3 {
+		t.Errorf("invalid protocol version, expected 4.3 got %v.%v.", major, minor)
+	} else if id := info.ConnectionID(); id != "bolt-42" {
+		t.Errorf("invalid connection ID, expected %v got %v.", "bolt-42", id)
+	} else if !reflect.DeepEqual(info.ServerHints(), hints) {
+		t.Errorf("invalid hints, expected %v got %v.", hints, info.ServerHints())
+	}
+}
+
+func TestConn_Reauthenticate(t *testing.T) {
+	var (
+		wr bytes.Buffer
+		rd bytes.Buffer
+	)
+	c := testMockConn(t, &rd, &wr)
+	defer c.Close()
+
+	if err := c.Reauthenticate("a", "b", "c"); err != ErrReauthUnsupported {
+		t.Errorf("error should be %v on Bolt v1, got %v.", ErrReauthUnsupported, err)
+	}
+
+	c.version = 0x0105
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	data := testGetEncodedMessage(t, packstream.NewStructure(byteLogoff))
+	data = append(data, testGetEncodedMessage(t, packstream.NewStructure(byteLogon, map[string]interface{}{
+		"scheme":      "a",
+		"principal":   "b",
+		"credentials": "c",
+	}))...)
+	if err := c.Reauthenticate("a", "b", "c"); err != nil {
+		t.Error(err)
+	} else if len(data) != len(wr.Bytes()) {
+		t.Errorf("Unexpected logoff and logon messages, expected length of %# x got %# x.", len(data), len(wr.Bytes()))
+	}
+
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteFailure, map[string]interface{}{"code": unauthorizedCode, "message": "hello"})))
+	if err := c.Reauthenticate("a", "b", "invalid"); err != types.ErrUnauthorized {
+		t.Errorf("error should be %v when credentials are invalid, got %v.", types.ErrUnauthorized, err)
+	} else if c.state != stateDefunct {
+		t.Error("connection should be defunct when re-authentication failed.")
+	}
 }
 
 func TestConn_Begin(t *testing.T) {
@@ -142,25 +328,111 @@ func TestConn_Begin(t *testing.T) {
 	c := testMockConn(t, &rd, &wr)
 	defer c.Close()
 
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{}})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{}})))
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
 	data := testGetEncodedMessage(t, packstream.NewStructure(byteRun, "BEGIN", map[string]interface{}{}))
-	data = append(data, testGetEncodedMessage(t, packstream.NewStructure(bytePullAll))...)
+	data = append(data, testGetEncodedMessage(t, packstream.NewStructure(byteDiscardAll))...)
+	data = append(data, testGetEncodedMessage(t, packstream.NewStructure(byteRun, "COMMIT", map[string]interface{}{}))...)
+	data = append(data, testGetEncodedMessage(t, packstream.NewStructure(byteDiscardAll))...)
 	if c.tx != nil {
 		t.Errorf("transaction should be nil, got %v.", c.tx)
 	} else if tx, err := c.Begin(); err != nil {
 		t.Error(err)
 	} else if tx == nil {
 		t.Error("got an unexpected nil transaction.")
-	} else if !bytes.Equal(wr.Bytes(), data) {
-		t.Errorf("Unexpected transaction message, expected %# x got %# x.", data, wr.Bytes())
+	} else if wr.Len() != 0 {
+		t.Errorf("transaction message should be sent along the next query, got %# x.", wr.Bytes())
 	} else if _, err := c.Begin(); err == nil {
 		t.Error("error should not be nil when a transaction has already been opened.")
+	} else if err = tx.Commit(); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(wr.Bytes(), data) {
+		t.Errorf("Unexpected transaction messages, expected %# x got %# x.", data, wr.Bytes())
+	} else if rd.Len() != 0 {
+		t.Errorf("all responses should have been read, %v bytes remaining.", rd.Len())
+	}
+
+	// The transaction failure is returned by the first query.
+	wr.Reset()
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteFailure, map[string]interface{}{"code": "42", "message": "hello"})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteIgnored)))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteIgnored)))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteIgnored)))
+	if _, err := c.Begin(); err != nil {
+		t.Error(err)
+	} else if _, err = c.run(context.Background(), "RETURN 1", map[string]interface{}{}, false); err == nil {
+		t.Error("error should not be nil when the transaction failed to begin.")
+	} else if c.state != stateFailed {
+		t.Errorf("state should be %v, got %v.", stateFailed, c.state)
+	} else if len(c.inflight) != 3 {
+		t.Errorf("%v requests should be pending, got %v.", 3, len(c.inflight))
+	}
+}
+
+func TestConn_BeginTx(t *testing.T) {
+	var (
+		wr bytes.Buffer
+		rd bytes.Buffer
+	)
+	c := testMockConn(t, &rd, &wr)
+	defer c.Close()
+
+	ctx := WithTxMetadata(context.Background(), map[string]interface{}{"service": "billing"})
+	if _, err := c.BeginTx(ctx, driver.TxOptions{}); err != ErrTxConfigUnsupported {
+		t.Errorf("error should be %v on Bolt v1, got %v.", ErrTxConfigUnsupported, err)
+	} else if _, err := c.BeginTx(context.Background(), driver.TxOptions{ReadOnly: true}); err != ErrReadOnlyUnsupported {
+		t.Errorf("error should be %v, got %v.", ErrReadOnlyUnsupported, err)
+	} else if _, err := c.BeginTx(context.Background(), driver.TxOptions{Isolation: driver.IsolationLevel(sql.LevelSerializable)}); err != ErrIsolationUnsupported {
+		t.Errorf("error should be %v, got %v.", ErrIsolationUnsupported, err)
+	}
+
+	// A read-only transaction is sent with the read access mode.
+	c.version = 3
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	data := testGetEncodedMessage(t, packstream.NewStructure(byteBegin, map[string]interface{}{"mode": "r"}))
+	data = append(data, testGetEncodedMessage(t, packstream.NewStructure(byteCommit))...)
+	opts := driver.TxOptions{Isolation: driver.IsolationLevel(sql.LevelReadCommitted), ReadOnly: true}
+	if tx, err := c.BeginTx(context.Background(), opts); err != nil {
+		t.Error(err)
+	} else if err = tx.Commit(); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(wr.Bytes(), data) {
+		t.Errorf("Unexpected transaction messages, expected %# x got %# x.", data, wr.Bytes())
+	}
+
+	wr.Reset()
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{}})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	data = testGetEncodedMessage(t, packstream.NewStructure(byteBegin, map[string]interface{}{"tx_metadata": map[string]interface{}{"service": "billing"}}))
+	data = append(data, testGetEncodedMessage(t, packstream.NewStructure(byteRun, "CREATE (n)", map[string]interface{}{}, map[string]interface{}{}))...)
+	data = append(data, testGetEncodedMessage(t, packstream.NewStructure(byteDiscardAll))...)
+	if _, err := c.BeginTx(ctx, driver.TxOptions{}); err != nil {
+		t.Error(err)
+	} else if c.state != stateTxReady {
+		t.Errorf("state should be %v, got %v.", stateTxReady, c.state)
+	} else if _, err = c.exec(context.Background(), "CREATE (n)", map[string]interface{}{}); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(wr.Bytes(), data) {
+		t.Errorf("Unexpected transaction messages, expected %# x got %# x.", data, wr.Bytes())
+	}
+
+	// A query cannot run once the transaction has been rolled back because of a failure.
+	c.state = stateFailed
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	if _, err := c.exec(context.Background(), "CREATE (n)", map[string]interface{}{}); err != ErrTransactionFailed {
+		t.Errorf("error should be %v, got %v.", ErrTransactionFailed, err)
 	}
 }
 
 func TestConn_Close(t *testing.T) {
-	c := testMockConn(t, new(bytes.Buffer), new(bytes.Buffer))
+	var wr bytes.Buffer
+	c := testMockConn(t, new(bytes.Buffer), &wr)
+	c.version = 3
+	data := testGetEncodedMessage(t, packstream.NewStructure(byteGoodbye))
 	if err := c.Close(); err != nil {
 		t.Error(err)
 	} else if c.wr != nil {
@@ -169,6 +441,78 @@ func TestConn_Close(t *testing.T) {
 		t.Errorf("connection reader should be nil, got %v", c.rd)
 	} else if c.tx != nil {
 		t.Errorf("connection transaction should be nil, got %v", c.tx)
+	} else if !bytes.Equal(wr.Bytes(), data) {
+		t.Errorf("Unexpected goodbye message, expected %# x got %# x.", data, wr.Bytes())
+	}
+}
+
+func TestConn_Ping(t *testing.T) {
+	var (
+		wr bytes.Buffer
+		rd bytes.Buffer
+	)
+	c := testMockConn(t, &rd, &wr)
+	defer c.Close()
+
+	// On Bolt v1, a query is run.
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{"1"}})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	data := testGetEncodedMessage(t, c.runMessage(context.Background(), "RETURN 1", map[string]interface{}{}))
+	data = append(data, testGetEncodedMessage(t, c.discardMessage(-1, -1))...)
+	if err := c.Ping(context.Background()); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(wr.Bytes(), data) {
+		t.Errorf("unexpected messages, expected %# x got %# x.", data, wr.Bytes())
+	}
+
+	// On Bolt v3 and above, a RESET message is sent.
+	wr.Reset()
+	c.version = 3
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	data = testGetEncodedMessage(t, packstream.NewStructure(byteReset))
+	if err := c.Ping(context.Background()); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(wr.Bytes(), data) {
+		t.Errorf("unexpected messages, expected %# x got %# x.", data, wr.Bytes())
+	} else if c.state != stateReady {
+		t.Errorf("state should be %v, got %v.", stateReady, c.state)
+	}
+
+	// A dead server is reported as a bad connection.
+	if err := c.Ping(context.Background()); err != driver.ErrBadConn {
+		t.Errorf("error should be %v, got %v.", driver.ErrBadConn, err)
+	} else if c.IsValid() {
+		t.Error("a defunct connection should not be valid.")
+	}
+}
+
+func TestConn_ResetSession(t *testing.T) {
+	var (
+		wr bytes.Buffer
+		rd bytes.Buffer
+	)
+	c := testMockConn(t, &rd, &wr)
+	defer c.Close()
+	c.version = 3
+
+	// A transaction left open is rolled back.
+	c.tx = &tx{conn: c}
+	c.state = stateTxReady
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	data := testGetEncodedMessage(t, packstream.NewStructure(byteRollback))
+	if err := c.ResetSession(context.Background()); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(wr.Bytes(), data) {
+		t.Errorf("unexpected messages, expected %# x got %# x.", data, wr.Bytes())
+	} else if c.tx != nil {
+		t.Errorf("connection transaction should be nil, got %v", c.tx)
+	} else if !c.IsValid() {
+		t.Error("a ready connection should be valid.")
+	}
+
+	c.state = stateDefunct
+	if err := c.ResetSession(context.Background()); err != driver.ErrBadConn {
+		t.Errorf("error should be %v, got %v.", driver.ErrBadConn, err)
 	}
 }
 
@@ -190,21 +534,81 @@ func TestConn_Prepare(t *testing.T) {
 	}
 }
 
+// testLogger is a Logger keeping the lines logged, for testing purposes.
+type testLogger []string
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	*l = append(*l, fmt.Sprintf(format, v...))
+}
+
+func TestConn_PrepareContext(t *testing.T) {
+	var (
+		wr bytes.Buffer
+		rd bytes.Buffer
+	)
+	c := testMockConn(t, &rd, &wr)
+	defer c.Close()
+	c.explain = true
+
+	// The query is explained, its columns and parameters are recorded.
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{"n"}})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"type": "r", "plan": map[string]interface{}{}})))
+	data := testGetEncodedMessage(t, c.runMessage(context.Background(), "EXPLAIN MATCH (n) WHERE n.name = {0} RETURN n", map[string]interface{}{}))
+	data = append(data, testGetEncodedMessage(t, c.discardMessage(-1, -1))...)
+	if stm, err := c.PrepareContext(context.Background(), "MATCH (n) WHERE n.name = {0} RETURN n"); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(wr.Bytes(), data) {
+		t.Errorf("unexpected messages, expected %# x got %# x.", data, wr.Bytes())
+	} else if n := stm.NumInput(); n != 1 {
+		t.Errorf("invalid number of inputs, expected %v got %v.", 1, n)
+	} else if columns := stm.(PreparedStatement).Columns(); !reflect.DeepEqual(columns, []string{"n"}) {
+		t.Errorf("invalid columns, expected [n] got %v.", columns)
+	}
+
+	// The notifications of the parameters, not sent along EXPLAIN, are not logged.
+	var logger testLogger
+	c.logger = &logger
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{"a", "b"}})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"type": "r", "notifications": []interface{}{
+		map[string]interface{}{"code": "Neo.ClientNotification.Statement.ParameterNotProvided"},
+		map[string]interface{}{"code": "Neo.ClientNotification.Statement.CartesianProduct"},
+	}})))
+	if _, err := c.PrepareContext(context.Background(), "MATCH (a), (b) WHERE a.name = $name RETURN a, b"); err != nil {
+		t.Error(err)
+	} else if len(logger) != 1 || !strings.Contains(logger[0], "CartesianProduct") {
+		t.Errorf("only the CartesianProduct notification should be logged, got %v.", logger)
+	}
+	c.logger = nil
+
+	// An invalid query fails right away.
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteFailure, map[string]interface{}{"code": "Neo.ClientError.Statement.SyntaxError", "message": "Invalid input"})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteIgnored)))
+	if _, err := c.PrepareContext(context.Background(), "RETRUN 1"); err == nil {
+		t.Error("error should not be nil when the query is not valid.")
+	} else if _, ok := err.(*types.CypherError); !ok {
+		t.Errorf("error should be a CypherError, got %T.", err)
+	}
+
+	// A query which is not explained has no known columns.
+	c.explain = false
+	if stm, err := c.PrepareContext(context.Background(), "RETURN 1"); err != nil {
+		t.Error(err)
+	} else if columns := stm.(PreparedStatement).Columns(); columns != nil {
+		t.Errorf("columns should be nil when the query is not explained, got %v.", columns)
+	}
+}
+
 func TestConn_Run(t *testing.T) {
 	var (
 		wr bytes.Buffer
 		rd bytes.Buffer
 	)
 	c := testMakeConn(t)
-	if res, err := c.run("CREATE (n {username: {username}}) RETURN n", map[string]interface{}{"username": "tester"}); err != nil {
-		t.Error(err)
-	} else if res == nil {
-		t.Error("statement result should not be nil.")
-	} else if len(res.Fields) != 1 || res.Fields[0] != "n" {
-		t.Errorf("unexpected fields response, expected %v, got %v", []string{"n"}, res.Fields)
-	} else if len(res.Rows) != 1 {
-		t.Errorf("expected %v rows, got %v", 1, len(res.Rows))
-	} else if node, ok := res.Rows[0]["n"]; !ok {
+	if res, err := testRunAll(c, "CREATE (n {username: {username}}) RETURN n", map[string]interface{}{"username": "tester"}); err != nil {
+		t.Error(err)
+	} else if len(res) != 1 {
+		t.Errorf("expected %v rows, got %v", 1, len(res))
+	} else if node, ok := res[0]["n"]; !ok {
 		t.Error("row in statement result should have property n.")
 	} else if node, ok := node.(*types.Node); !ok {
 		t.Error("row in statement result should be a Node.")
@@ -215,54 +619,64 @@ func TestConn_Run(t *testing.T) {
 	}
 	c.Close()
 
-	// Invalid server responses
+	// Invalid server responses, each of them leaves the connection defunct so its state is restored before the next
+	// one.
 	c = testMockConn(t, &rd, &wr)
 	defer c.Close()
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess)))
-	if _, err := c.run("CREATE (n {username: {username}}) RETURN n", nil); err == nil {
+	if _, err := testRunAll(c, "CREATE (n {username: {username}}) RETURN n", nil); err == nil {
 		t.Error("error should not be nil when message response does not have enough fields.")
 	}
+	c.state, c.inflight = stateReady, nil
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, []interface{}{})))
-	if _, err := c.run("CREATE (n {username: {username}}) RETURN n", nil); err == nil {
+	if _, err := testRunAll(c, "CREATE (n {username: {username}}) RETURN n", nil); err == nil {
 		t.Error("error should not be nil when message response first field is not a map.")
 	}
+	c.state, c.inflight = stateReady, nil
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
-	if _, err := c.run("CREATE (n {username: {username}}) RETURN n", nil); err == nil {
+	if _, err := testRunAll(c, "CREATE (n {username: {username}}) RETURN n", nil); err == nil {
 		t.Error("error should not be nil when message response does not contains 'fields' map key.")
 	}
+	c.state, c.inflight = stateReady, nil
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": "hello"})))
-	if _, err := c.run("CREATE (n {username: {username}}) RETURN n", nil); err == nil {
+	if _, err := testRunAll(c, "CREATE (n {username: {username}}) RETURN n", nil); err == nil {
 		t.Error("error should not be nil when message response does not contains fields are not a list.")
 	}
+	c.state, c.inflight = stateReady, nil
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{42}})))
-	if _, err := c.run("CREATE (n {username: {username}}) RETURN n", nil); err == nil {
+	if _, err := testRunAll(c, "CREATE (n {username: {username}}) RETURN n", nil); err == nil {
 		t.Error("error should not be nil when fields are not strings.")
 	}
+	c.state, c.inflight = stateReady, nil
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{"testField"}})))
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteRecord)))
-	if _, err := c.run("CREATE (n {username: {username}}) RETURN n", nil); err == nil {
+	if _, err := testRunAll(c, "CREATE (n {username: {username}}) RETURN n", nil); err == nil {
 		t.Error("error should not be nil when received record does not have enough fields.")
 	}
+	c.state, c.inflight = stateReady, nil
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{"testField"}})))
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteRecord, 42)))
-	if _, err := c.run("CREATE (n {username: {username}}) RETURN n", nil); err == nil {
+	if _, err := testRunAll(c, "CREATE (n {username: {username}}) RETURN n", nil); err == nil {
 		t.Error("error should not be nil when received record does not contains a list.")
 	}
+	c.state, c.inflight = stateReady, nil
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{"testField"}})))
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteRecord, []interface{}{"testField", "invalid"})))
-	if _, err := c.run("CREATE (n {username: {username}}) RETURN n", nil); err == nil {
+	if _, err := testRunAll(c, "CREATE (n {username: {username}}) RETURN n", nil); err == nil {
 		t.Error("error should not be nil when received record have too much fields.")
 	}
+	c.state, c.inflight = stateReady, nil
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{"testField"}})))
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteInit)))
-	if _, err := c.run("CREATE (n {username: {username}}) RETURN n", nil); err == nil {
+	if _, err := testRunAll(c, "CREATE (n {username: {username}}) RETURN n", nil); err == nil {
 		t.Error("error should not be nil when record response is invalid")
 	}
+	c.state, c.inflight = stateReady, nil
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{"testField"}})))
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteRecord, []interface{}{"testField1"})))
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteRecord, []interface{}{"testField2"})))
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
-	if _, err := c.run("CREATE (n {username: {username}}) RETURN n", nil); err != nil {
+	if _, err := testRunAll(c, "CREATE (n {username: {username}}) RETURN n", nil); err != nil {
 		t.Errorf("Error should be nil on valid run response, got %v", err)
 	}
 
@@ -271,7 +685,162 @@ func TestConn_Run(t *testing.T) {
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteRecord, []interface{}{"testField1"})))
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteRecord, []interface{}{"testField2"})))
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, nil)))
-	if _, err := c.run("CREATE (n {username: {username}}) RETURN n", nil); err == nil {
+	if _, err := testRunAll(c, "CREATE (n {username: {username}}) RETURN n", nil); err == nil {
 		t.Error("Error should not be nil on invalid summary.")
 	}
 }
+
+func TestConn_exec(t *testing.T) {
+	var (
+		wr bytes.Buffer
+		rd bytes.Buffer
+	)
+	c := testMockConn(t, &rd, &wr)
+	defer c.Close()
+	c.version = 0x0404
+
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{"n"}, "qid": int64(1)})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"type": "w"})))
+	data := testGetEncodedMessage(t, c.runMessage(context.Background(), "CREATE (n) RETURN n", map[string]interface{}{}))
+	data = append(data, testGetEncodedMessage(t, c.discardMessage(-1, -1))...)
+	if res, err := c.exec(context.Background(), "CREATE (n) RETURN n", map[string]interface{}{}); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(wr.Bytes(), data) {
+		t.Errorf("unexpected messages, expected %# x got %# x.", data, wr.Bytes())
+	} else if res.Type != "w" {
+		t.Errorf("invalid summary type, expected %v got %v.", "w", res.Type)
+	} else if c.state != stateReady {
+		t.Errorf("state should be %v, got %v.", stateReady, c.state)
+	} else if c.stream != nil {
+		t.Errorf("no result should be streaming, got %v.", c.stream)
+	}
+}
+
+func TestConn_runMessage(t *testing.T) {
+	c := new(conn)
+	c.version = 1
+	if msg := c.runMessage(context.Background(), "RETURN 1", nil); len(msg.Fields) != 2 {
+		t.Errorf("Bolt v1 RUN message should have %v fields, got %v.", 2, len(msg.Fields))
+	}
+
+	c.version = 3
+	c.database = "sales"
+	if msg := c.runMessage(context.Background(), "RETURN 1", nil); len(msg.Fields) != 3 {
+		t.Errorf("Bolt v3 RUN message should have %v fields, got %v.", 3, len(msg.Fields))
+	} else if extra, ok := msg.Fields[2].(map[string]interface{}); !ok {
+		t.Error("Bolt v3 RUN message metadata should be a map.")
+	} else if _, ok := extra["db"]; ok {
+		t.Error("Bolt v3 RUN message metadata should not contain a database.")
+	}
+
+	c.version = 0x0404
+	if msg := c.runMessage(context.Background(), "RETURN 1", nil); len(msg.Fields) != 3 {
+		t.Errorf("Bolt v4 RUN message should have %v fields, got %v.", 3, len(msg.Fields))
+	} else if extra, ok := msg.Fields[2].(map[string]interface{}); !ok {
+		t.Error("Bolt v4 RUN message metadata should be a map.")
+	} else if extra["db"] != "sales" {
+		t.Errorf("invalid database, expected %v got %v.", "sales", extra["db"])
+	}
+}
+
+func TestConn_pullMessage(t *testing.T) {
+	c := new(conn)
+	c.version = 3
+	if msg := c.pullMessage(-1, -1); msg.Signature != bytePullAll || len(msg.Fields) != 0 {
+		t.Errorf("Bolt v3 should use PULL_ALL, got %v.", msg)
+	}
+	if msg := c.discardMessage(-1, -1); msg.Signature != byteDiscardAll || len(msg.Fields) != 0 {
+		t.Errorf("Bolt v3 should use DISCARD_ALL, got %v.", msg)
+	}
+
+	c.version = 0x0004
+	if msg := c.pullMessage(100, -1); !reflect.DeepEqual(msg.Fields, []interface{}{map[string]interface{}{"n": int64(100)}}) {
+		t.Errorf("unexpected Bolt v4 PULL message, got %v.", msg)
+	}
+	if msg := c.discardMessage(-1, 2); !reflect.DeepEqual(msg.Fields, []interface{}{map[string]interface{}{"n": int64(-1), "qid": int64(2)}}) {
+		t.Errorf("unexpected Bolt v4 DISCARD message, got %v.", msg)
+	}
+}
+
+func TestConn_extra(t *testing.T) {
+	c := new(conn)
+	c.version = 0x0404
+	c.database = "sales"
+	c.impUser = "bob"
+
+	expected := map[string]interface{}{"db": "sales", "imp_user": "bob"}
+	if extra := c.extra(context.Background()); !reflect.DeepEqual(extra, expected) {
+		t.Errorf("invalid metadata, expected %v got %v.", expected, extra)
+	}
+	ctx := WithImpersonatedUser(WithDatabase(context.Background(), "hr"), "alice")
+	expected = map[string]interface{}{"db": "hr", "imp_user": "alice"}
+	if extra := c.extra(ctx); !reflect.DeepEqual(extra, expected) {
+		t.Errorf("invalid metadata, expected %v got %v.", expected, extra)
+	}
+	ctx = WithDatabase(context.Background(), "")
+	expected = map[string]interface{}{"imp_user": "bob"}
+	if extra := c.extra(ctx); !reflect.DeepEqual(extra, expected) {
+		t.Errorf("invalid metadata, expected %v got %v.", expected, extra)
+	}
+
+	c.version = 0x0304
+	if err := c.checkTarget(context.Background()); err != ErrImpersonationUnsupported {
+		t.Errorf("error should be %v on Bolt v4.3, got %v.", ErrImpersonationUnsupported, err)
+	}
+	c.version = 3
+	c.impUser = ""
+	if err := c.checkTarget(context.Background()); err != ErrDatabaseUnsupported {
+		t.Errorf("error should be %v on Bolt v3, got %v.", ErrDatabaseUnsupported, err)
+	} else if err = c.checkTarget(WithDatabase(context.Background(), "")); err != nil {
+		t.Error(err)
+	}
+
+	// A routed connection cannot run against another database than the connection string one.
+	c.version = 0x0404
+	c.database = "sales"
+	c.table = new(routingTable)
+	if err := c.checkTarget(WithDatabase(context.Background(), "hr")); err != ErrRoutedDatabase {
+		t.Errorf("error should be %v on a routed connection, got %v.", ErrRoutedDatabase, err)
+	} else if err = c.checkTarget(WithDatabase(context.Background(), "sales")); err != nil {
+		t.Error(err)
+	} else if err = c.checkTarget(context.Background()); err != nil {
+		t.Error(err)
+	}
+	c.table = nil
+}
+
+func TestConn_QueryContext(t *testing.T) {
+	var (
+		wr bytes.Buffer
+		rd bytes.Buffer
+	)
+	c := testMockConn(t, &rd, &wr)
+	defer c.Close()
+	c.version = 0x0404
+
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{}})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	ctx := WithDatabase(context.Background(), "sales")
+	data := testGetEncodedMessage(t, packstream.NewStructure(byteRun, "RETURN $0", map[string]interface{}{"0": int64(1)}, map[string]interface{}{"db": "sales"}))
+	data = append(data, testGetEncodedMessage(t, c.pullMessage(c.fetchSize, -1))...)
+	if rows, err := c.QueryContext(ctx, "RETURN {0}", []driver.NamedValue{{Ordinal: 1, Value: int64(1)}}); err != nil {
+		t.Error(err)
+	} else if err = rows.Close(); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(wr.Bytes(), data) {
+		t.Errorf("unexpected messages, expected %# x got %# x.", data, wr.Bytes())
+	}
+
+	wr.Reset()
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{}})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"stats": map[string]interface{}{"nodes-created": int64(1)}})))
+	data = testGetEncodedMessage(t, packstream.NewStructure(byteRun, "RETURN $a", map[string]interface{}{"a": int64(1)}, map[string]interface{}{"db": "sales"}))
+	data = append(data, testGetEncodedMessage(t, c.discardMessage(-1, -1))...)
+	if res, err := c.ExecContext(ctx, "RETURN $a", []driver.NamedValue{{Name: "a", Ordinal: 1, Value: int64(1)}}); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(wr.Bytes(), data) {
+		t.Errorf("unexpected messages, expected %# x got %# x.", data, wr.Bytes())
+	} else if n, _ := res.RowsAffected(); n != 1 {
+		t.Errorf("invalid rows affected, expected %v got %v.", 1, n)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build go1.10
+// +build go1.10
+
+package neoql
+
+import "database/sql/driver"
+
+// NewConnector returns a connector opening the connections configured by "config", to be passed to sql.OpenDB. Its
+// connections share a BookmarkManager. It returns an error if the connection string is not valid.
+func NewConnector(config Config) (driver.Connector, error) {
+	return defaultDriver.newConnector(config, new(BookmarkManager))
+}
+
+// OpenConnector implements the OpenConnector() method of the sql/driver.DriverContext interface.
+// The connection string is parsed once, instead of each time a connection is opened.
+func (d *neoDriver) OpenConnector(name string) (driver.Connector, error) {
+	return d.newConnector(Config{Target: name}, d.bookmarkManager(name))
+}
+
+// Driver implements the Driver() method of the sql/driver.Connector interface.
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
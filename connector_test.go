@@ -0,0 +1,46 @@
+//go:build go1.10
+// +build go1.10
+
+package neoql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestNewConnector(t *testing.T) {
+	if _, err := NewConnector(Config{Target: "http://localhost:7687"}); err != ErrBadScheme {
+		t.Errorf("error should be %v, got %v.", ErrBadScheme, err)
+	}
+
+	connector, err := NewConnector(Config{Target: testNeo4jURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err = conn.Raw(func(driverConn interface{}) error {
+		if manager := driverConn.(Bookmarker).BookmarkManager(); manager == nil {
+			t.Error("the connections of a connector should share a bookmark manager.")
+		}
+		return nil
+	}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNeoDriver_OpenConnector(t *testing.T) {
+	d := &neoDriver{}
+	connector, err := d.OpenConnector(testNeo4jURL)
+	if err != nil {
+		t.Fatal(err)
+	} else if connector.Driver() != d {
+		t.Error("the connector driver should be the one opening it.")
+	}
+}
@@ -0,0 +1,104 @@
+package neoql
+
+import (
+	"context"
+	"time"
+)
+
+// contextKey is the type of the context keys defined by the neoql driver.
+type contextKey int
+
+const (
+	txMetadataKey          contextKey = iota // Key of the transaction metadata.
+	txTimeoutKey                             // Key of the transaction timeout.
+	bookmarksKey                             // Key of the bookmarks to wait for.
+	databaseKey                              // Key of the database the queries run against.
+	impersonatedUserKey                      // Key of the user the queries run as.
+	notificationHandlerKey                   // Key of the notification handler.
+	notificationFiltersKey                   // Key of the notification filters.
+	scriptKey                                // Key of the script flag.
+)
+
+// WithTxMetadata returns a copy of ctx carrying the transaction metadata. When the context is passed to the BeginTx()
+// method of a sql.DB or a sql.Conn, the metadata are attached to the transaction, so they are listed along it by
+// "dbms.listTransactions" or "SHOW TRANSACTIONS". It requires Bolt v3 or above.
+func WithTxMetadata(ctx context.Context, metadata map[string]interface{}) context.Context {
+	return context.WithValue(ctx, txMetadataKey, metadata)
+}
+
+// WithTxTimeout returns a copy of ctx carrying the transaction timeout. When the context is passed to the BeginTx()
+// method of a sql.DB or a sql.Conn, the server terminates the transaction once it runs longer than the timeout. The
+// timeout is sent with a millisecond precision. It requires Bolt v3 or above.
+func WithTxTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, txTimeoutKey, timeout)
+}
+
+// WithBookmarks returns a copy of ctx carrying bookmarks. When the context is passed to the BeginTx() method of a
+// sql.DB or a sql.Conn, the server waits for the transactions identified by these bookmarks, in addition to the ones
+// of the BookmarkManager, before beginning the new transaction. It requires Bolt v3 or above.
+func WithBookmarks(ctx context.Context, bookmarks ...string) context.Context {
+	return context.WithValue(ctx, bookmarksKey, bookmarks)
+}
+
+// WithDatabase returns a copy of ctx carrying a database name. When the context is passed to the QueryContext(),
+// ExecContext() or BeginTx() methods of a sql.DB or a sql.Conn, the queries run against this database instead of the
+// one of the connection string. An empty name selects the server default database. It requires Bolt v4 or above.
+// With the "neo4j" URL schemes, the database cannot differ from the connection string one: ErrRoutedDatabase is
+// returned, since the server is picked from the routing table of the connection string database.
+func WithDatabase(ctx context.Context, database string) context.Context {
+	return context.WithValue(ctx, databaseKey, database)
+}
+
+// WithImpersonatedUser returns a copy of ctx carrying a user name. When the context is passed to the QueryContext(),
+// ExecContext() or BeginTx() methods of a sql.DB or a sql.Conn, the queries run as this user instead of the one of
+// the connection string, with the user privileges. An empty name selects the authenticated user. It requires Bolt
+// v4.4 or above, and the impersonation privilege.
+func WithImpersonatedUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, impersonatedUserKey, user)
+}
+
+// WithNotificationHandler returns a copy of ctx carrying a notification handler. When the context is passed to the
+// QueryContext() or ExecContext() methods of a sql.DB, a sql.Conn or a sql.Tx, the handler is called with each
+// notification returned by the server, once the result of the query is read.
+func WithNotificationHandler(ctx context.Context, handler NotificationHandler) context.Context {
+	return context.WithValue(ctx, notificationHandlerKey, handler)
+}
+
+// WithNotificationFilters returns a copy of ctx carrying notification filters. When the context is passed to the
+// QueryContext(), ExecContext() or BeginTx() methods of a sql.DB or a sql.Conn, the filters replace the ones of the
+// connection string: the server only returns the notifications whose severity is at least "minSeverity", "OFF"
+// disabling all of them, and whose category is not one of "disabledCategories". An empty severity keeps the server
+// default one. It requires Bolt v5.2 or above.
+func WithNotificationFilters(ctx context.Context, minSeverity string, disabledCategories ...string) context.Context {
+	filter := notificationFilter{minSeverity: minSeverity, disabledCategories: disabledCategories}
+	if filter.disabledCategories == nil {
+		filter.disabledCategories = []string{}
+	}
+	return context.WithValue(ctx, notificationFiltersKey, filter)
+}
+
+// WithScript returns a copy of ctx carrying the script flag. When the context is passed to the QueryContext() or
+// ExecContext() methods of a sql.DB, a sql.Conn or a sql.Tx, the query is a script whose statements are separated by
+// semicolons. They run in order on the same connection, with the same parameters, and the result of each statement is
+// a result set of the returned sql.Rows.
+func WithScript(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scriptKey, true)
+}
+
+// contextBookmarks returns the bookmarks carried by ctx, if any.
+func contextBookmarks(ctx context.Context) []string {
+	bookmarks, _ := ctx.Value(bookmarksKey).([]string)
+	return bookmarks
+}
+
+// txConfig returns the transaction configuration carried by ctx, as sent in the BEGIN message metadata.
+func txConfig(ctx context.Context) map[string]interface{} {
+	config := map[string]interface{}{}
+	if metadata, ok := ctx.Value(txMetadataKey).(map[string]interface{}); ok {
+		config["tx_metadata"] = metadata
+	}
+	if timeout, ok := ctx.Value(txTimeoutKey).(time.Duration); ok {
+		config["tx_timeout"] = int64(timeout / time.Millisecond)
+	}
+	return config
+}
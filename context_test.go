@@ -0,0 +1,21 @@
+package neoql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTxConfig(t *testing.T) {
+	if config := txConfig(context.Background()); len(config) != 0 {
+		t.Errorf("configuration should be empty, got %v.", config)
+	}
+
+	metadata := map[string]interface{}{"service": "billing"}
+	ctx := WithTxTimeout(WithTxMetadata(context.Background(), metadata), 3*time.Second)
+	expected := map[string]interface{}{"tx_metadata": metadata, "tx_timeout": int64(3000)}
+	if config := txConfig(ctx); !reflect.DeepEqual(config, expected) {
+		t.Errorf("invalid configuration, expected %v got %v.", expected, config)
+	}
+}
@@ -1,34 +1,105 @@
 package neoql
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"encoding/binary"
 	"errors"
-	"io"
-	"net"
-	"net/url"
+	"sync"
 )
 
 // neoDriver is the sql/driver.Driver implementation.
 type neoDriver struct {
+	mu        sync.Mutex
+	bookmarks map[string]*BookmarkManager // bookmark managers, by connection string.
+	tables    map[string]*routingTable    // routing tables, by cluster address and database.
 }
 
-
 // ErrTransactionStarted is returned when a user calls begin and a transaction has already been started
 // on this connection.
 var ErrTransactionStarted = errors.New("open: No protocol version could be agreed")
 
+// ErrBadScheme is returned when the URL scheme of the connection string is not supported.
+var ErrBadScheme = errors.New("open: Unsupported URL scheme, it must be 'bolt' or 'neo4j', optionally followed by '+s' or '+ssc'")
+
 // ErrBadVersion is returned when no protocol version could be agreed with the Neo4j server.
 var ErrBadVersion = errors.New("open: No protocol version could be agreed")
 
+// ErrReauthUnsupported is returned when re-authenticating a connection whose protocol version does not support it.
+var ErrReauthUnsupported = errors.New("reauthenticate: Re-authentication requires Bolt v5.1 or above")
+
+// ErrDatabaseUnsupported is returned when a database is selected and the protocol version agreed with the Neo4j
+// server does not support multiple databases.
+var ErrDatabaseUnsupported = errors.New("open: Database selection requires Bolt v4 or above")
+
+// ErrRoutedDatabase is returned when a query or a transaction selects another database than the one of the
+// connection string on a routed connection, whose server is picked from the routing table of this database.
+var ErrRoutedDatabase = errors.New("query: The database of a routed connection is the one of its connection string")
+
+// ErrImpersonationUnsupported is returned when a user is impersonated and the protocol version agreed with the Neo4j
+// server does not support impersonation.
+var ErrImpersonationUnsupported = errors.New("open: User impersonation requires Bolt v4.4 or above")
+
+// ErrMixedArgs is returned when some query parameters are passed with sql.Named, and others by position.
+var ErrMixedArgs = errors.New("query: Positional and named parameters cannot be mixed")
+
+// ErrTxConfigUnsupported is returned when beginning a transaction with metadata or a timeout, and the protocol version
+// agreed with the Neo4j server does not support them.
+var ErrTxConfigUnsupported = errors.New("begin: Transaction metadata and timeout require Bolt v3 or above")
+
+// ErrIsolationUnsupported is returned when beginning a transaction with an isolation level other than the default or
+// the read committed one, which is the isolation level of Neo4j transactions.
+var ErrIsolationUnsupported = errors.New("begin: Unsupported isolation level, Neo4j transactions are read committed")
+
+// ErrReadOnlyUnsupported is returned when beginning a read-only transaction, and the protocol version agreed with the
+// Neo4j server does not support access modes.
+var ErrReadOnlyUnsupported = errors.New("begin: Read-only transactions require Bolt v3 or above")
+
+// ErrTransactionFailed is returned when using a transaction which has been rolled back by the Neo4j server, because
+// one of its queries failed.
+var ErrTransactionFailed = errors.New("tx: The transaction failed and has been rolled back")
+
 var (
 	// magicPreamble is the required preamble to initialize the connection with a Neo4j server.
 	magicPreamble = []byte{0x60, 0x60, 0xB0, 0x17}
-	// versions supported and the supported Bolt protocol versions supported by the neoql driver.
-	versionsSupported = [4]uint32{1, 0, 0, 0}
+	// versionsSupported are the Bolt protocol versions supported by the neoql driver, by order of preference.
+	// Since Bolt v4.1, a version is encoded as [0x00, range, minor, major], where "range" is the number of preceding
+	// minor versions also supported. Neo4j 4.0 and 4.1 ignore the range and only read the version 4.4, so they agree
+	// on Bolt v3: sending Bolt v4.0 on its own would take the slot of Bolt v3, which Neo4j 3.5 requires.
+	versionsSupported = [4]uint32{
+		0x00040405, // 5.4 to 5.0
+		0x00040404, // 4.4 to 4.0
+		0x00000003, // 3
+		0x00000001, // 1
+	}
+	// defaultDriver is the driver registered as "neo4j-bolt".
+	defaultDriver = &neoDriver{}
 	// handshakeRequest is the bytes representation of the supported versions.
 	handshakeRequest [16]byte
+	// schemes are the supported URL schemes, associated to true if the connections are routed within a cluster. The
+	// "+s" suffix enables TLS, and "+ssc" enables TLS without verifying the server certificate.
+	schemes = map[string]bool{
+		"bolt":      false,
+		"bolt+s":    false,
+		"bolt+ssc":  false,
+		"neo4j":     true,
+		"neo4j+s":   true,
+		"neo4j+ssc": true,
+	}
+	// dsnParameters are the connection string parameters handled by the driver, the other ones being sent to the
+	// cluster as the routing context.
+	dsnParameters = map[string]bool{
+		"database":                          true,
+		"fetch_size":                        true,
+		"imp_user":                          true,
+		"mode":                              true,
+		"notifications_min_severity":        true,
+		"notifications_disabled_categories": true,
+		"placeholders":                      true,
+		"legacy_params":                     true,
+		"prepare":                           true,
+	}
 )
 
 // init computes the supported versions into a byte slice and register the driver.
@@ -37,62 +108,69 @@ func init() {
 		binary.BigEndian.PutUint32(handshakeRequest[i*4:i*4+4], v)
 	}
 
-	sql.Register("neo4j-bolt", &neoDriver{})
+	sql.Register("neo4j-bolt", defaultDriver)
 }
 
 // Open implements the Open() method of the sql/driver.Driver interface.
-// It sends the preamble to the Neo4j server, checks the supported versions, then initializes the connection by
-// authenticating to server.
-func (d *neoDriver) Open(name string) (c driver.Conn, err error) {
-	var (
-		conn     net.Conn
-		URL      *url.URL
-		username string
-		password string
-		bVersion [4]byte
-		version  uint32
-	)
-
-	if URL, err = url.Parse(name); err != nil {
-		return nil, err
-	}
-	if URL.Scheme != "bolt" {
-		return nil, errors.New("Only the 'bolt' URL scheme is supported")
-	}
-	if URL.User != nil {
-		username = URL.User.Username()
-		password, _ = URL.User.Password()
-	}
-	if conn, err = net.Dial("tcp", URL.Host); err != nil {
-		return nil, err
-	}
-	if _, err := conn.Write(magicPreamble); err != nil {
-		return nil, err
-	}
-	if _, err := conn.Write(handshakeRequest[:]); err != nil {
-		return nil, err
-	}
-	if _, err := io.ReadFull(conn, bVersion[:]); err != nil {
+// The connection string is parsed, then the connection is opened as by a connector configured with this string only.
+func (d *neoDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.newConnector(Config{Target: name}, d.bookmarkManager(name))
+	if err != nil {
 		return nil, err
 	}
-	if version = binary.BigEndian.Uint32(bVersion[:]); version == 0 {
-		return nil, ErrBadVersion
+	return c.Connect(context.Background())
+}
+
+// bookmarkManager returns the bookmark manager shared by the connections opened with the "name" connection string,
+// hence by the connections of a sql.DB.
+func (d *neoDriver) bookmarkManager(name string) *BookmarkManager {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.bookmarks == nil {
+		d.bookmarks = make(map[string]*BookmarkManager)
 	}
-	if version != 1 {
-		return nil, ErrBadVersion
+	if _, ok := d.bookmarks[name]; !ok {
+		d.bookmarks[name] = new(BookmarkManager)
 	}
-	if c, err = newConn(conn, "basic", username, password); err != nil {
-		return nil, err
-	}
-	return
+	return d.bookmarks[name]
 }
 
-/*
-This type implements MarshalPS to write bytes without packstream byte slice encoding.
-*/
-type rawBytes []byte
+// versionMajor returns the major part of a Bolt protocol version.
+func versionMajor(version uint32) uint32 {
+	return version & 0xFF
+}
 
-// MarshalPS implements the packstream.Marshaler interface
-func (b rawBytes) MarshalPS() ([]byte, error) {
-	return []byte(b), nil
+// versionMinor returns the minor part of a Bolt protocol version.
+func versionMinor(version uint32) uint32 {
+	return (version >> 8) & 0xFF
+}
+
+// versionRange returns the number of preceding minor versions covered by a Bolt protocol version sent in the
+// handshake.
+func versionRange(version uint32) uint32 {
+	return (version >> 16) & 0xFF
+}
+
+// versionAtLeast returns true if the Bolt protocol version is greater than or equal to major.minor.
+func versionAtLeast(version, major, minor uint32) bool {
+	if versionMajor(version) != major {
+		return versionMajor(version) > major
+	}
+	return versionMinor(version) >= minor
+}
+
+// isVersionSupported returns true if the version returned by the server is one of the versions sent in the handshake.
+func isVersionSupported(version uint32) bool {
+	if versionRange(version) != 0 {
+		return false
+	}
+	for _, v := range versionsSupported {
+		if v == 0 || versionMajor(v) != versionMajor(version) {
+			continue
+		}
+		if versionMinor(version) <= versionMinor(v) && versionMinor(version)+versionRange(v) >= versionMinor(v) {
+			return true
+		}
+	}
+	return false
 }
@@ -18,7 +18,7 @@ func TestOpen(t *testing.T) {
 		c.Close()
 	}
 
-	if c, err := (&neoDriver{}).Open(strings.Replace(testNeo4jURL, "bolt", "http", 1)); err == nil || err.Error() != "Only the 'bolt' URL scheme is supported" {
+	if c, err := (&neoDriver{}).Open(strings.Replace(testNeo4jURL, "bolt", "http", 1)); err != ErrBadScheme {
 		t.Errorf("Error when scheme is invalid is not valid: %v.", err)
 	} else if c != nil {
 		t.Error("Connection should be nil when scheme is invalid.")
@@ -31,6 +31,51 @@ func TestOpen(t *testing.T) {
 	}
 }
 
+func TestIsVersionSupported(t *testing.T) {
+	if !isVersionSupported(1) {
+		t.Error("version 1 should be supported.")
+	}
+	if !isVersionSupported(3) {
+		t.Error("version 3 should be supported.")
+	}
+	if isVersionSupported(0) {
+		t.Error("version 0 should not be supported.")
+	}
+	if isVersionSupported(2) {
+		t.Error("version 2 should not be supported.")
+	}
+	for _, v := range []uint32{0x0004, 0x0104, 0x0204, 0x0304, 0x0404} {
+		if !isVersionSupported(v) {
+			t.Errorf("version %v.%v should be supported.", versionMajor(v), versionMinor(v))
+		}
+	}
+	if isVersionSupported(0x0504) {
+		t.Error("version 4.5 should not be supported.")
+	}
+	for _, v := range []uint32{0x0005, 0x0105, 0x0205, 0x0305, 0x0405} {
+		if !isVersionSupported(v) {
+			t.Errorf("version %v.%v should be supported.", versionMajor(v), versionMinor(v))
+		}
+	}
+	if isVersionSupported(0x00020404) {
+		t.Error("a version range should not be accepted as a server response.")
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	if !versionAtLeast(0x0404, 4, 4) {
+		t.Error("version 4.4 should be at least 4.4.")
+	} else if !versionAtLeast(0x0404, 3, 0) {
+		t.Error("version 4.4 should be at least 3.0.")
+	} else if versionAtLeast(0x0404, 5, 0) {
+		t.Error("version 4.4 should not be at least 5.0.")
+	} else if versionAtLeast(0x0204, 4, 3) {
+		t.Error("version 4.2 should not be at least 4.3.")
+	} else if versionAtLeast(1, 3, 0) {
+		t.Error("version 1 should not be at least 3.0.")
+	}
+}
+
 type testUser struct {
 	ID       uint64
 	Username string
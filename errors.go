@@ -8,6 +8,10 @@ import (
 const (
 	// unauthorizedCode is the error code returned by Neo4j when authentication failed.
 	unauthorizedCode = "Neo.ClientError.Security.Unauthorized"
+	// notALeaderCode is the error code returned by a Neo4j cluster member which is not the leader, on write.
+	notALeaderCode = "Neo.ClientError.Cluster.NotALeader"
+	// forbiddenOnReadOnlyCode is the error code returned by a Neo4j cluster member which cannot write the database.
+	forbiddenOnReadOnlyCode = "Neo.ClientError.General.ForbiddenOnReadOnlyDatabase"
 )
 
 // getMessageError reads the packstream Structure, and returns an error if the message signature is a failure.
@@ -49,3 +53,9 @@ func messageError(res *packstream.Structure, defaultErr error) error {
 	}
 	return defaultErr
 }
+
+// isNotALeader returns true if the "code" error code means the server cannot write, so writes must be sent to another
+// cluster member.
+func isNotALeader(code string) bool {
+	return code == notALeaderCode || code == forbiddenOnReadOnlyCode
+}
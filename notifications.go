@@ -0,0 +1,120 @@
+package neoql
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// ErrNotificationFilterUnsupported is returned when notifications are filtered, and the protocol version agreed with
+// the Neo4j server does not support notification filters.
+var ErrNotificationFilterUnsupported = errors.New("open: Notification filters require Bolt v5.2 or above")
+
+// parameterNotProvided ends the code of the notification returned when a query references a parameter which is not
+// sent, like "Neo.ClientNotification.Statement.ParameterNotProvided".
+const parameterNotProvided = ".ParameterNotProvided"
+
+// notificationSeverities are the minimum severities of the notifications which can be set to filter them.
+var notificationSeverities = map[string]bool{"OFF": true, "WARNING": true, "INFORMATION": true}
+
+// Notification is a notification returned by the Neo4j server along the result of a query, like the use of a
+// deprecated feature or a query plan which may perform poorly.
+type Notification struct {
+	Code        string         // Code is the notification code, like "Neo.ClientNotification.Statement.FeatureDeprecationWarning".
+	Title       string         // Title is a short summary of the notification.
+	Description string         // Description is the detailed description of the notification.
+	Severity    string         // Severity is the notification severity, "WARNING" or "INFORMATION".
+	Category    string         // Category is the notification category, like "DEPRECATION" or "PERFORMANCE", set since Neo4j 5.
+	Position    *InputPosition // Position is the position in the query the notification refers to, if any.
+}
+
+// InputPosition is a position in a Cypher query.
+type InputPosition struct {
+	Offset int64 // Offset is the character offset, starting at 0.
+	Line   int64 // Line is the line number, starting at 1.
+	Column int64 // Column is the column number, starting at 1.
+}
+
+// NotificationHandler is a function called with each notification returned by the Neo4j server, once the result of
+// the query is read. It is set with WithNotificationHandler.
+type NotificationHandler func(Notification)
+
+// parseNotifications returns the notifications of a query summary. Invalid notifications are skipped.
+func parseNotifications(v interface{}) []Notification {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	notifications := make([]Notification, 0, len(list))
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		n := Notification{}
+		n.Code, _ = m["code"].(string)
+		n.Title, _ = m["title"].(string)
+		n.Description, _ = m["description"].(string)
+		n.Severity, _ = m["severity"].(string)
+		n.Category, _ = m["category"].(string)
+		if position, ok := m["position"].(map[string]interface{}); ok {
+			n.Position = new(InputPosition)
+			n.Position.Offset, _ = position["offset"].(int64)
+			n.Position.Line, _ = position["line"].(int64)
+			n.Position.Column, _ = position["column"].(int64)
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications
+}
+
+// notificationFilter is the notification filter carried by a context, set with WithNotificationFilters.
+type notificationFilter struct {
+	minSeverity        string
+	disabledCategories []string
+}
+
+// notificationFilters returns the notification filters sent in the HELLO message, read from the
+// "notifications_min_severity" and "notifications_disabled_categories" connection string parameters.
+func notificationFilters(query url.Values) (map[string]interface{}, error) {
+	var categories []string
+	if _, ok := query["notifications_disabled_categories"]; ok {
+		categories = []string{}
+		for _, category := range strings.Split(query.Get("notifications_disabled_categories"), ",") {
+			if category = strings.TrimSpace(category); category != "" {
+				categories = append(categories, category)
+			}
+		}
+	}
+	return makeNotificationFilters(query.Get("notifications_min_severity"), categories)
+}
+
+// makeNotificationFilters returns the notification filters sent in the HELLO, BEGIN or RUN messages, made of the
+// "severity" minimum severity, unless empty, and of the "categories" disabled categories, unless nil.
+func makeNotificationFilters(severity string, categories []string) (map[string]interface{}, error) {
+	filters := map[string]interface{}{}
+	if severity != "" {
+		if severity = strings.ToUpper(severity); !notificationSeverities[severity] {
+			return nil, errors.New("open: Invalid notifications_min_severity, it must be OFF, WARNING or INFORMATION")
+		}
+		filters["notifications_minimum_severity"] = severity
+	}
+	if categories != nil {
+		disabled := make([]string, 0, len(categories))
+		for _, category := range categories {
+			disabled = append(disabled, strings.ToUpper(category))
+		}
+		filters["notifications_disabled_categories"] = disabled
+	}
+	return filters, nil
+}
+
+// contextNotificationFilters returns the notification filters carried by ctx, if any.
+func contextNotificationFilters(ctx context.Context) (map[string]interface{}, error) {
+	filter, ok := ctx.Value(notificationFiltersKey).(notificationFilter)
+	if !ok {
+		return nil, nil
+	}
+	return makeNotificationFilters(filter.minSeverity, filter.disabledCategories)
+}
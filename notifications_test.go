@@ -0,0 +1,121 @@
+package neoql
+
+import (
+	"bytes"
+	"context"
+	"gopkg.in/packstream.v1"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseNotifications(t *testing.T) {
+	if notifications := parseNotifications(nil); notifications != nil {
+		t.Errorf("notifications should be nil, got %v.", notifications)
+	}
+
+	notifications := parseNotifications([]interface{}{
+		map[string]interface{}{
+			"code":        "Neo.ClientNotification.Statement.FeatureDeprecationWarning",
+			"title":       "This feature is deprecated",
+			"description": "The query used a deprecated feature.",
+			"severity":    "WARNING",
+			"category":    "DEPRECATION",
+			"position":    map[string]interface{}{"offset": int64(7), "line": int64(1), "column": int64(8)},
+		},
+		42,
+		map[string]interface{}{"code": "Neo.ClientNotification.Statement.CartesianProduct", "severity": "INFORMATION"},
+	})
+	expected := []Notification{
+		{
+			Code:        "Neo.ClientNotification.Statement.FeatureDeprecationWarning",
+			Title:       "This feature is deprecated",
+			Description: "The query used a deprecated feature.",
+			Severity:    "WARNING",
+			Category:    "DEPRECATION",
+			Position:    &InputPosition{Offset: 7, Line: 1, Column: 8},
+		},
+		{Code: "Neo.ClientNotification.Statement.CartesianProduct", Severity: "INFORMATION"},
+	}
+	if !reflect.DeepEqual(notifications, expected) {
+		t.Errorf("invalid notifications, expected %v got %v.", expected, notifications)
+	}
+}
+
+func TestNotificationFilters(t *testing.T) {
+	if filters, err := notificationFilters(url.Values{}); err != nil {
+		t.Error(err)
+	} else if len(filters) != 0 {
+		t.Errorf("filters should be empty, got %v.", filters)
+	}
+
+	query := url.Values{"notifications_min_severity": {"warning"}, "notifications_disabled_categories": {"hint, unrecognized"}}
+	expected := map[string]interface{}{
+		"notifications_minimum_severity":    "WARNING",
+		"notifications_disabled_categories": []string{"HINT", "UNRECOGNIZED"},
+	}
+	if filters, err := notificationFilters(query); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(filters, expected) {
+		t.Errorf("invalid filters, expected %v got %v.", expected, filters)
+	}
+
+	query = url.Values{"notifications_disabled_categories": {""}}
+	expected = map[string]interface{}{"notifications_disabled_categories": []string{}}
+	if filters, err := notificationFilters(query); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(filters, expected) {
+		t.Errorf("invalid filters, expected %v got %v.", expected, filters)
+	}
+
+	if _, err := notificationFilters(url.Values{"notifications_min_severity": {"loud"}}); err == nil {
+		t.Error("error should not be nil on an invalid severity.")
+	}
+}
+
+func TestConn_notificationFilters(t *testing.T) {
+	c := new(conn)
+	c.version = 0x0205
+	ctx := WithNotificationFilters(context.Background(), "OFF")
+	expected := map[string]interface{}{
+		"notifications_minimum_severity":    "OFF",
+		"notifications_disabled_categories": []string{},
+	}
+	if err := c.checkTarget(ctx); err != nil {
+		t.Error(err)
+	} else if extra := c.extra(ctx); !reflect.DeepEqual(extra, expected) {
+		t.Errorf("invalid metadata, expected %v got %v.", expected, extra)
+	}
+
+	if err := c.checkTarget(WithNotificationFilters(context.Background(), "loud")); err == nil {
+		t.Error("error should not be nil on an invalid severity.")
+	}
+	c.version = 0x0105
+	if err := c.checkTarget(ctx); err != ErrNotificationFilterUnsupported {
+		t.Errorf("error should be %v on Bolt v5.1, got %v.", ErrNotificationFilterUnsupported, err)
+	}
+}
+
+func TestConn_notificationHandler(t *testing.T) {
+	var (
+		wr bytes.Buffer
+		rd bytes.Buffer
+	)
+	c := testMockConn(t, &rd, &wr)
+	defer c.Close()
+
+	var notifications []Notification
+	ctx := WithNotificationHandler(context.Background(), func(n Notification) {
+		notifications = append(notifications, n)
+	})
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{}})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{
+		"notifications": []interface{}{map[string]interface{}{"code": "Neo.ClientNotification.Statement.CartesianProduct"}},
+	})))
+	expected := []Notification{{Code: "Neo.ClientNotification.Statement.CartesianProduct"}}
+	if _, err := c.ExecContext(ctx, "MATCH (a), (b) RETURN a, b", nil); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(notifications, expected) {
+		t.Errorf("invalid notifications, expected %v got %v.", expected, notifications)
+	}
+}
@@ -0,0 +1,340 @@
+package neoql
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	placeholdersQuestion = "question" // Placeholders like "?", replaced by "$0", "$1"... in order.
+	placeholdersDollar   = "dollar"   // Placeholders like "$1", starting at 1, replaced by "$0".
+	placeholdersColon    = "colon"    // Placeholders like ":name", replaced by "$name".
+)
+
+const (
+	legacyParamsRewrite = ""       // The legacy "{name}" parameters are rewritten on Bolt v4 and above.
+	legacyParamsStrict  = "strict" // As legacyParamsRewrite, ambiguous parameters being reported as errors.
+	legacyParamsOff     = "off"    // The legacy parameters are never rewritten.
+)
+
+// placeholderStyles are the placeholder styles which can be rewritten into Cypher parameters.
+var placeholderStyles = map[string]bool{
+	placeholdersQuestion: true,
+	placeholdersDollar:   true,
+	placeholdersColon:    true,
+}
+
+// legacyParamsModes are the modes of the legacy parameters rewriting.
+var legacyParamsModes = map[string]bool{
+	legacyParamsRewrite: true,
+	legacyParamsStrict:  true,
+	legacyParamsOff:     true,
+}
+
+// ErrBadPlaceholder is returned when a "$0" placeholder is used with the "dollar" placeholder style, whose
+// placeholders start at 1.
+var ErrBadPlaceholder = errors.New("query: Invalid placeholder $0, the dollar placeholders start at $1")
+
+// ErrAmbiguousParameter is returned in the strict legacy parameters mode, when "{name}" follows a variable outside of a
+// certain pattern, so it may be a parameter in a pattern, or a map projection, like in "RETURN (n {name})".
+var ErrAmbiguousParameter = errors.New("query: Ambiguous {name} legacy parameter, it may be a map projection")
+
+// cypherKeywords are the reserved Cypher keywords. A colon following another word starts a label, a relationship
+// type or the value of a map entry, and a parenthesis following another word opens the arguments of a function.
+var cypherKeywords = map[string]bool{
+	"ALL": true, "AND": true, "AS": true, "ASC": true, "ASCENDING": true, "BY": true, "CALL": true, "CASE": true,
+	"CONTAINS": true, "CREATE": true, "DELETE": true, "DESC": true, "DESCENDING": true, "DETACH": true,
+	"DISTINCT": true, "ELSE": true, "END": true, "ENDS": true, "EXISTS": true, "FOREACH": true, "IN": true, "IS": true,
+	"LIMIT": true, "MATCH": true, "MERGE": true, "NOT": true, "ON": true, "OPTIONAL": true, "OR": true, "ORDER": true,
+	"REMOVE": true, "RETURN": true, "SET": true, "SKIP": true, "STARTS": true, "THEN": true, "UNION": true,
+	"UNWIND": true, "WHEN": true, "WHERE": true, "WITH": true, "XOR": true, "YIELD": true,
+}
+
+// tokenKind is the kind of a Cypher token.
+type tokenKind int
+
+const (
+	tokenSpace     tokenKind = iota // Spaces and line breaks.
+	tokenComment                    // A "//" or "/* */" comment.
+	tokenString                     // A single or double quoted string literal.
+	tokenQuoted                     // A backquoted identifier.
+	tokenWord                       // An identifier, a keyword or a number.
+	tokenParameter                  // A "$name" parameter.
+	tokenPunct                      // Any other character.
+)
+
+// token is a Cypher token, as returned by tokenize.
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// significant returns true if the token is neither a space nor a comment.
+func (t token) significant() bool {
+	return t.kind != tokenSpace && t.kind != tokenComment
+}
+
+// isVariable returns true if the token may be a variable, a label or a function name, rather than a keyword.
+func (t token) isVariable() bool {
+	return t.kind == tokenQuoted || (t.kind == tokenWord && isIdentifierStart(t.text[0]) && !cypherKeywords[strings.ToUpper(t.text)])
+}
+
+// tokenize splits the "query" Cypher query into tokens, so it can be rewritten without altering the string literals,
+// the quoted identifiers and the comments. Concatenating the tokens returns the query.
+func tokenize(query string) []token {
+	var tokens []token
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		t := token{kind: tokenPunct}
+		end := i + 1
+		switch {
+		case c == '\'' || c == '"':
+			t.kind, end = tokenString, quoteEnd(query, i)
+		case c == '`':
+			t.kind, end = tokenQuoted, quoteEnd(query, i)
+		case strings.HasPrefix(query[i:], "//"):
+			t.kind, end = tokenComment, len(query)
+			if n := strings.IndexByte(query[i:], '\n'); n >= 0 {
+				end = i + n
+			}
+		case strings.HasPrefix(query[i:], "/*"):
+			t.kind, end = tokenComment, len(query)
+			if n := strings.Index(query[i+2:], "*/"); n >= 0 {
+				end = i + n + 4
+			}
+		case isIdentifierPart(c):
+			t.kind, end = tokenWord, identifierEnd(query, i)
+		case c == '$' && i+1 < len(query) && isIdentifierPart(query[i+1]):
+			t.kind, end = tokenParameter, identifierEnd(query, i+1)
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			t.kind = tokenSpace
+			for end < len(query) && strings.IndexByte(" \t\n\r", query[end]) >= 0 {
+				end++
+			}
+		}
+		t.text = query[i:end]
+		tokens = append(tokens, t)
+		i = end
+	}
+	return tokens
+}
+
+// rewritePlaceholders returns the "query" Cypher query whose placeholders of the "style" style are replaced by Cypher
+// parameters. The string literals, the quoted identifiers and the comments are left as they are.
+func rewritePlaceholders(query, style string) (string, error) {
+	var (
+		out bytes.Buffer
+		// previous is the last significant token. It tells whether a colon starts a placeholder or a label.
+		previous token
+		next     int
+	)
+
+	tokens := tokenize(query)
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.kind == tokenParameter && style == placeholdersDollar && isNumber(t.text[1:]):
+			n, err := strconv.Atoi(t.text[1:])
+			if err != nil {
+				return "", err
+			} else if n == 0 {
+				return "", ErrBadPlaceholder
+			}
+			t.text = "$" + strconv.Itoa(n-1)
+		case t.text == "?" && style == placeholdersQuestion:
+			t = token{kind: tokenParameter, text: "$" + strconv.Itoa(next)}
+			next++
+		case t.text == ":" && style == placeholdersColon && i+1 < len(tokens) && tokens[i+1].kind == tokenWord &&
+			isIdentifierStart(tokens[i+1].text[0]) && !startsLabel(previous):
+			i++
+			t = token{kind: tokenParameter, text: "$" + tokens[i].text}
+		}
+		out.WriteString(t.text)
+		if t.significant() {
+			previous = t
+		}
+	}
+	return out.String(), nil
+}
+
+// startsLabel returns true if a colon following the "previous" token starts a label or a relationship type, like in
+// "(n:Person)", "(:Person)", "[:KNOWS|:LIKES]" or "(n:Person:Actor)", rather than a placeholder.
+func startsLabel(previous token) bool {
+	switch previous.kind {
+	case tokenQuoted:
+		return true
+	case tokenWord:
+		return !cypherKeywords[strings.ToUpper(previous.text)]
+	case tokenPunct:
+		return strings.Contains("([|&!)]", previous.text)
+	}
+	return false
+}
+
+// patternKeywords are the Cypher keywords followed by a pattern: a parenthesis following them can only open a node
+// pattern, rather than a parenthesized expression.
+var patternKeywords = map[string]bool{"CREATE": true, "MATCH": true, "MERGE": true}
+
+// bracket is a parenthesis or a bracket opened in a Cypher query.
+type bracket struct {
+	pattern bool // pattern is true if the bracket may open a node or a relationship pattern.
+	certain bool // certain is true if the bracket can only open a pattern, like after MATCH or "-".
+}
+
+// rewriteLegacyParams returns the "query" Cypher query whose legacy "{name}" parameters are replaced by "$name"
+// parameters, the legacy syntax being removed since Neo4j 4.0. A "{name}" following a variable is ambiguous: it is
+// rewritten within a node or relationship pattern, and kept as a map projection otherwise. If "strict" is true,
+// ErrAmbiguousParameter is returned instead, unless the enclosing bracket can only open a pattern, like in
+// "CREATE (n {props})" or "-[r {props}]-".
+func rewriteLegacyParams(query string, strict bool) (string, error) {
+	var (
+		out      bytes.Buffer
+		previous token
+		brackets []bracket
+	)
+
+	tokens := tokenize(query)
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch t.text {
+		case "(":
+			certain := (previous.kind == tokenWord && patternKeywords[strings.ToUpper(previous.text)]) ||
+				(previous.kind == tokenPunct && strings.Contains("-<>)", previous.text))
+			brackets = append(brackets, bracket{pattern: !previous.isVariable(), certain: certain})
+		case "[":
+			pattern := previous.text == "-" || previous.text == "<"
+			brackets = append(brackets, bracket{pattern: pattern, certain: pattern})
+		case ")", "]":
+			if len(brackets) != 0 {
+				brackets = brackets[:len(brackets)-1]
+			}
+		case "{":
+			name, end := legacyParam(tokens, i)
+			if end < 0 {
+				break
+			}
+			if previous.isVariable() && !isNumber(name) {
+				var enclosing bracket
+				if len(brackets) != 0 {
+					enclosing = brackets[len(brackets)-1]
+				}
+				if strict && !enclosing.certain {
+					return "", ErrAmbiguousParameter
+				} else if !enclosing.pattern {
+					break
+				}
+			}
+			t = token{kind: tokenParameter, text: "$" + name}
+			i = end
+		}
+		out.WriteString(t.text)
+		if t.significant() {
+			previous = t
+		}
+	}
+	return out.String(), nil
+}
+
+// legacyParam returns the name of the legacy parameter whose opening brace is the token "start", and the index of
+// its closing brace. The index is -1 if the brace opens a map rather than a parameter.
+func legacyParam(tokens []token, start int) (string, int) {
+	var name string
+
+	for i := start + 1; i < len(tokens); i++ {
+		switch t := tokens[i]; {
+		case t.kind == tokenSpace:
+		case name == "" && (t.kind == tokenWord || t.kind == tokenQuoted):
+			name = t.text
+		case name != "" && t.text == "}":
+			return name, i
+		default:
+			return "", -1
+		}
+	}
+	return "", -1
+}
+
+// numParameters returns the number of arguments expected by the "query" Cypher query: if its parameters are numbered,
+// like "$0" and "$1", the highest number plus one, and the number of distinct parameters otherwise. The legacy
+// "{name}" parameters are counted too.
+func numParameters(query string) int {
+	var (
+		names    = map[string]bool{}
+		numbered = true
+		max      = -1
+	)
+
+	query, _ = rewriteLegacyParams(query, false)
+	for _, t := range tokenize(query) {
+		if t.kind != tokenParameter {
+			continue
+		}
+		name := t.text[1:]
+		names[name] = true
+		if n, err := strconv.Atoi(name); err == nil && isNumber(name) {
+			if n > max {
+				max = n
+			}
+		} else {
+			numbered = false
+		}
+	}
+	if numbered {
+		return max + 1
+	}
+	return len(names)
+}
+
+// quoteEnd returns the index following the string literal or the quoted identifier starting at "start". A quote is
+// escaped by a backslash in a string literal, and doubled in a quoted identifier.
+func quoteEnd(query string, start int) int {
+	quote := query[start]
+	for i := start + 1; i < len(query); i++ {
+		switch {
+		case query[i] == '\\' && quote != '`':
+			i++
+		case query[i] == quote && quote == '`' && i+1 < len(query) && query[i+1] == quote:
+			i++
+		case query[i] == quote:
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+// identifierEnd returns the index following the word starting at "start".
+func identifierEnd(query string, start int) int {
+	i := start
+	for i < len(query) && isIdentifierPart(query[i]) {
+		i++
+	}
+	return i
+}
+
+// isIdentifierStart returns true if "c" can start an identifier. The non-ASCII bytes are considered letters.
+func isIdentifierStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+// isIdentifierPart returns true if "c" can be part of an identifier.
+func isIdentifierPart(c byte) bool {
+	return isIdentifierStart(c) || isDigit(c)
+}
+
+// isDigit returns true if "c" is a decimal digit.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isNumber returns true if "s" is only made of decimal digits.
+func isNumber(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return s != ""
+}
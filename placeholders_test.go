@@ -0,0 +1,121 @@
+package neoql
+
+import (
+	"testing"
+)
+
+func TestRewritePlaceholders(t *testing.T) {
+	for _, test := range []struct {
+		style    string
+		query    string
+		expected string
+	}{
+		{placeholdersQuestion, "MATCH (n:User) WHERE n.name = ? AND n.age > ? RETURN n", "MATCH (n:User) WHERE n.name = $0 AND n.age > $1 RETURN n"},
+		{placeholdersQuestion, "RETURN '?', \"it\\\"s ?\", `?` // ?\n, ? /* ? */", "RETURN '?', \"it\\\"s ?\", `?` // ?\n, $0 /* ? */"},
+		{placeholdersDollar, "MATCH (n) WHERE n.name = $1 AND n.age > $2 RETURN n, $name", "MATCH (n) WHERE n.name = $0 AND n.age > $1 RETURN n, $name"},
+		{placeholdersDollar, "RETURN '$1'", "RETURN '$1'"},
+		{placeholdersColon, "MATCH (n:User {name: :name})-[:KNOWS|:LIKES]->(:User:Admin) WHERE n.age > :age RETURN n", "MATCH (n:User {name: $name})-[:KNOWS|:LIKES]->(:User:Admin) WHERE n.age > $age RETURN n"},
+		{placeholdersColon, "UNWIND :tags AS tag MATCH (n) WHERE tag IN :list RETURN n LIMIT :limit", "UNWIND $tags AS tag MATCH (n) WHERE tag IN $list RETURN n LIMIT $limit"},
+		{placeholdersColon, "MATCH (`my node`:User) RETURN ':name', n.`a:b`", "MATCH (`my node`:User) RETURN ':name', n.`a:b`"},
+	} {
+		if query, err := rewritePlaceholders(test.query, test.style); err != nil {
+			t.Error(err)
+		} else if query != test.expected {
+			t.Errorf("invalid query with the %s style, expected %q got %q.", test.style, test.expected, query)
+		}
+	}
+
+	if _, err := rewritePlaceholders("RETURN $0", placeholdersDollar); err != ErrBadPlaceholder {
+		t.Errorf("error should be %v, got %v.", ErrBadPlaceholder, err)
+	}
+}
+
+func TestRewriteLegacyParams(t *testing.T) {
+	for _, test := range []struct {
+		query    string
+		expected string
+	}{
+		{"MATCH (n) WHERE n.name = {0} AND n.age > { age } RETURN n", "MATCH (n) WHERE n.name = $0 AND n.age > $age RETURN n"},
+		{"CREATE (n:User {props})-[:KNOWS {since}]->(m {1}) RETURN n", "CREATE (n:User $props)-[:KNOWS $since]->(m $1) RETURN n"},
+		{"MATCH (n) RETURN n {.name, age: 1}, collect(n {name}), n {name}, {}", "MATCH (n) RETURN n {.name, age: 1}, collect(n {name}), n {name}, {}"},
+		{"RETURN '{0}', `{0}` // {0}", "RETURN '{0}', `{0}` // {0}"},
+	} {
+		if query, err := rewriteLegacyParams(test.query, false); err != nil {
+			t.Error(err)
+		} else if query != test.expected {
+			t.Errorf("invalid query, expected %q got %q.", test.expected, query)
+		}
+	}
+
+	if query, err := rewriteLegacyParams("MATCH (n) WHERE n.name = {0} RETURN n", true); err != nil {
+		t.Error(err)
+	} else if query != "MATCH (n) WHERE n.name = $0 RETURN n" {
+		t.Errorf("invalid query in the strict mode, got %q.", query)
+	}
+
+	// In the strict mode, a "{name}" following a variable is only rewritten where a pattern is certain.
+	for _, test := range []struct {
+		query    string
+		expected string
+	}{
+		{"CREATE (n:Person {props}) RETURN n", "CREATE (n:Person $props) RETURN n"},
+		{"OPTIONAL MATCH (n)-[r:KNOWS {since}]->(m {props}) RETURN r", "OPTIONAL MATCH (n)-[r:KNOWS $since]->(m $props) RETURN r"},
+		{"MATCH (n) WHERE exists((n)-[:R {w}]->()) RETURN n", "MATCH (n) WHERE exists((n)-[:R $w]->()) RETURN n"},
+	} {
+		if query, err := rewriteLegacyParams(test.query, true); err != nil {
+			t.Error(err)
+		} else if query != test.expected {
+			t.Errorf("invalid query in the strict mode, expected %q got %q.", test.expected, query)
+		}
+	}
+	for _, query := range []string{"RETURN (n {name})", "MATCH (n) RETURN n {name}", "MATCH (a), (b {props}) RETURN a"} {
+		if _, err := rewriteLegacyParams(query, true); err != ErrAmbiguousParameter {
+			t.Errorf("error should be %v for %q, got %v.", ErrAmbiguousParameter, query, err)
+		}
+	}
+}
+
+func TestNumParameters(t *testing.T) {
+	for _, test := range []struct {
+		query    string
+		expected int
+	}{
+		{"RETURN 1", 0},
+		{"RETURN $0, $1, $0", 2},
+		{"RETURN $2", 3},
+		{"MATCH (n {name: $name}) WHERE n.age > $age RETURN n, $name", 2},
+		{"MATCH (n) WHERE n.name = {0} RETURN n {name}, '$1'", 1},
+	} {
+		if n := numParameters(test.query); n != test.expected {
+			t.Errorf("invalid number of parameters of %q, expected %v got %v.", test.query, test.expected, n)
+		}
+	}
+}
+
+func TestConn_rewrite(t *testing.T) {
+	c := new(conn)
+	if query, err := c.rewrite("RETURN ?"); err != nil {
+		t.Error(err)
+	} else if query != "RETURN ?" {
+		t.Errorf("the query should not be rewritten without placeholder style, got %q.", query)
+	}
+	c.style = placeholdersQuestion
+	if query, err := c.rewrite("RETURN ?"); err != nil {
+		t.Error(err)
+	} else if query != "RETURN $0" {
+		t.Errorf("invalid query, expected %q got %q.", "RETURN $0", query)
+	}
+
+	c.style = ""
+	if query, _ := c.rewrite("RETURN {0}"); query != "RETURN {0}" {
+		t.Errorf("legacy parameters should not be rewritten on Bolt v1, got %q.", query)
+	}
+	c.version = 0x0004
+	if query, _ := c.rewrite("RETURN {0}"); query != "RETURN $0" {
+		t.Errorf("legacy parameters should be rewritten on Bolt v4, got %q.", query)
+	}
+	c.legacy = legacyParamsOff
+	if query, _ := c.rewrite("RETURN {0}"); query != "RETURN {0}" {
+		t.Errorf("legacy parameters should not be rewritten when disabled, got %q.", query)
+	}
+}
@@ -11,41 +11,147 @@ import (
 // rows is the representation of records returned by a Cypher query.
 type rows []map[string]interface{}
 
-// result implements the sql/driver.Result interface.
-type result struct {
+// Result implements the sql/driver.Result interface. It is returned by the ExecContext() method of the connections,
+// so the update counters of a query can be read through the Raw() method of a sql.Conn.
+type Result struct {
+	Counters Counters
+}
+
+// Counters are the update counters of a query, as reported by the server in its summary.
+type Counters struct {
+	NodesCreated          int64
+	NodesDeleted          int64
+	RelationshipsCreated  int64
+	RelationshipsDeleted  int64
+	PropertiesSet         int64
+	LabelsAdded           int64
+	LabelsRemoved         int64
+	IndexesAdded          int64
+	IndexesRemoved        int64
+	ConstraintsAdded      int64
+	ConstraintsRemoved    int64
+	SystemUpdates         int64 // SystemUpdates is the number of updates of the system database (Bolt v4+).
+	ContainsUpdates       bool  // ContainsUpdates is true if the query updated the database.
+	ContainsSystemUpdates bool  // ContainsSystemUpdates is true if the query updated the system database (Bolt v4+).
+}
+
+// parseCounters returns the update counters of the "stats" map of a summary. Before Bolt v5, the server does not send
+// the "contains-updates" flags, which are then computed from the counters.
+func parseCounters(stats interface{}) Counters {
+	m, _ := stats.(map[string]interface{})
+	count := func(key string) int64 {
+		n, _ := m[key].(int64)
+		return n
+	}
+	c := Counters{
+		NodesCreated:         count("nodes-created"),
+		NodesDeleted:         count("nodes-deleted"),
+		RelationshipsCreated: count("relationships-created"),
+		RelationshipsDeleted: count("relationships-deleted"),
+		PropertiesSet:        count("properties-set"),
+		LabelsAdded:          count("labels-added"),
+		LabelsRemoved:        count("labels-removed"),
+		IndexesAdded:         count("indexes-added"),
+		IndexesRemoved:       count("indexes-removed"),
+		ConstraintsAdded:     count("constraints-added"),
+		ConstraintsRemoved:   count("constraints-removed"),
+		SystemUpdates:        count("system-updates"),
+	}
+	var ok bool
+	if c.ContainsUpdates, ok = m["contains-updates"].(bool); !ok {
+		c.ContainsUpdates = c.updates() != 0
+	}
+	if c.ContainsSystemUpdates, ok = m["contains-system-updates"].(bool); !ok {
+		c.ContainsSystemUpdates = c.SystemUpdates != 0
+	}
+	return c
+}
+
+// add adds the "other" counters to the counters.
+func (c *Counters) add(other Counters) {
+	c.NodesCreated += other.NodesCreated
+	c.NodesDeleted += other.NodesDeleted
+	c.RelationshipsCreated += other.RelationshipsCreated
+	c.RelationshipsDeleted += other.RelationshipsDeleted
+	c.PropertiesSet += other.PropertiesSet
+	c.LabelsAdded += other.LabelsAdded
+	c.LabelsRemoved += other.LabelsRemoved
+	c.IndexesAdded += other.IndexesAdded
+	c.IndexesRemoved += other.IndexesRemoved
+	c.ConstraintsAdded += other.ConstraintsAdded
+	c.ConstraintsRemoved += other.ConstraintsRemoved
+	c.SystemUpdates += other.SystemUpdates
+	c.ContainsUpdates = c.ContainsUpdates || other.ContainsUpdates
+	c.ContainsSystemUpdates = c.ContainsSystemUpdates || other.ContainsSystemUpdates
+}
+
+// updates returns the number of updates of the database, which is the sum of its counters, the system updates aside.
+func (c Counters) updates() int64 {
+	return c.NodesCreated + c.NodesDeleted + c.RelationshipsCreated + c.RelationshipsDeleted + c.PropertiesSet +
+		c.LabelsAdded + c.LabelsRemoved + c.IndexesAdded + c.IndexesRemoved + c.ConstraintsAdded + c.ConstraintsRemoved
 }
 
 // statementResult implements the sql/driver.Rows interface.
+// Its records are read from the connection on demand, unless another request is sent on the connection before the
+// end of the stream: the remaining records are then kept in Rows.
 type statementResult struct {
-	Fields  []string
-	Rows    rows
-	Type    string
-	Plan    map[string]interface{}
-	Profile map[string]interface{}
-	cursor  int
+	Fields        []string
+	Rows          rows
+	Type          string
+	Plan          map[string]interface{}
+	Profile       map[string]interface{}
+	Notifications []Notification
+	Counters      Counters
+	cursor        int
+	conn          *conn               // conn is the connection the records are read from.
+	qid           int64               // qid is the query ID used to pull records on Bolt v4 and above, -1 for the last query.
+	streaming     bool                // streaming is true until the end of the stream is read from the connection.
+	err           error               // err is the error which interrupted the stream while its records were kept in Rows.
+	autocommit    bool                // autocommit is true if the query runs outside of a transaction, so its summary has a bookmark.
+	handler       NotificationHandler // handler is called with the notifications of the summary, if not nil.
+	stop          func() error        // stop stops watching the context of the query, once the stream ends.
+	first         []interface{}       // first is the first record read, the column types being inferred from its values.
 }
 
 // LastInsertId implements the LastInsertId() method of the sql/driver.Result interface.
 // It is not supported by this driver.
-func (r *result) LastInsertId() (int64, error) {
+func (r *Result) LastInsertId() (int64, error) {
 	return 0, errors.New("LastInsertId is not supported by the Neo4j driver.")
 }
 
 // RowsAffected implements the RowsAffected() method of the sql/driver.Result interface.
-// It is not supported by this driver.
-func (r *result) RowsAffected() (int64, error) {
-	return 0, errors.New("RowsAffected is not supported.")
+// It returns the number of entities changed by the query: the nodes and relationships created or deleted. If there is
+// none, it returns the number of properties set, or else the number of labels added or removed.
+func (r *Result) RowsAffected() (int64, error) {
+	c := r.Counters
+	if n := c.NodesCreated + c.NodesDeleted + c.RelationshipsCreated + c.RelationshipsDeleted; n != 0 {
+		return n, nil
+	} else if c.PropertiesSet != 0 {
+		return c.PropertiesSet, nil
+	}
+	return c.LabelsAdded + c.LabelsRemoved, nil
 }
 
 // Close implements the Close() method of the sql/driver.Rows interface.
-func (r *statementResult) Close() error {
+// If records are still streamed, they are discarded so the connection can be used by another request.
+func (r *statementResult) Close() (err error) {
+	if r.streaming {
+		if _, err = r.fetch(true); err == io.EOF {
+			err = nil
+		}
+	}
 	r.Rows = nil
 	r.Fields = nil
 	r.Type = ""
 	r.Plan = nil
 	r.Profile = nil
+	r.Notifications = nil
+	r.Counters = Counters{}
+	r.first = nil
 	r.cursor = 0
-	return nil
+	r.conn = nil
+	r.err = nil
+	return err
 }
 
 // Columns implements the Columns() method of the sql/driver.Rows interface.
@@ -54,17 +160,143 @@ func (r *statementResult) Columns() []string {
 }
 
 // Next implements the Next() method of the sql/driver.Rows interface.
+// It returns the records kept in memory first, then it reads the next records from the connection.
 func (r *statementResult) Next(dest []driver.Value) error {
-	if r.cursor >= len(r.Rows) {
+	if r.cursor < len(r.Rows) {
+		for i, f := range r.Fields {
+			dest[i] = r.Rows[r.cursor][f]
+		}
+		r.cursor++
+		return nil
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if !r.streaming {
 		return io.EOF
 	}
-	for i, f := range r.Fields {
-		dest[i] = r.Rows[r.cursor][f]
+	record, err := r.fetch(false)
+	if err != nil {
+		return err
+	}
+	for i := range record {
+		dest[i] = record[i]
 	}
-	r.cursor++
 	return nil
 }
 
+// fetch reads the next record from the connection and converts its values. When the server reports more records
+// are available, it pulls the next batch, or discards the remaining records if "discard" is true, in which case the
+// records read are skipped.
+// At the end of the stream, it fills the statement summary and returns io.EOF.
+func (r *statementResult) fetch(discard bool) (_ []interface{}, err error) {
+	var (
+		res    *packstream.Structure
+		record []interface{}
+		convOK bool
+	)
+
+	defer func() {
+		if err != nil && r.stop != nil {
+			// The stream ends, the error being the context one if the query has been interrupted.
+			if ctxErr := r.stop(); ctxErr != nil {
+				err = ctxErr
+			}
+			r.stop = nil
+		}
+		if err != nil {
+			r.streaming = false
+			if r.conn.stream == r {
+				r.conn.stream = nil
+			}
+			if err == types.ErrProtocol {
+				r.conn.state = stateDefunct
+			}
+		}
+	}()
+	for {
+		if res, err = r.conn.readMessage(); err != nil {
+			return nil, err
+		} else if len(res.Fields) == 0 {
+			return nil, types.ErrProtocol
+		} else if res.Signature == byteRecord {
+			if discard {
+				continue
+			}
+			if record, convOK = res.Fields[0].([]interface{}); !convOK {
+				return nil, types.ErrProtocol
+			}
+			if len(r.Fields) != len(record) {
+				return nil, types.ErrProtocol
+			}
+			for i, item := range record {
+				if record[i], err = recordToType(item); err != nil {
+					return nil, err
+				}
+			}
+			if r.first == nil {
+				r.first = record
+			}
+			return record, nil
+		} else if res.Signature == byteSuccess && hasMore(res) {
+			next := r.conn.pullMessage(r.conn.fetchSize, r.qid)
+			if discard {
+				next = r.conn.discardMessage(-1, r.qid)
+			}
+			if err = r.conn.writeMessage(next); err != nil {
+				return nil, err
+			}
+		} else if res.Signature == byteSuccess {
+			if err = r.hydrateSummary(res); err != nil {
+				return nil, err
+			}
+			if r.autocommit {
+				r.conn.receiveBookmark(res)
+			}
+			if r.handler != nil {
+				for _, n := range r.Notifications {
+					r.handler(n)
+				}
+			}
+			return nil, io.EOF
+		} else {
+			return nil, messageError(res, types.ErrProtocol)
+		}
+	}
+}
+
+// buffer reads the remaining records from the connection and keeps them in Rows, so another request can be sent on
+// the connection. If the stream is interrupted by an error, it is returned by Next once all the records kept are
+// read.
+func (r *statementResult) buffer() {
+	for r.streaming {
+		record, err := r.fetch(false)
+		if err != nil {
+			if err != io.EOF {
+				r.err = err
+			}
+			return
+		}
+		row := make(map[string]interface{}, len(r.Fields))
+		for i, f := range r.Fields {
+			row[f] = record[i]
+		}
+		r.Rows = append(r.Rows, row)
+	}
+}
+
+// hasMore returns true if the summary message reports more records are available, on Bolt v4 and above.
+func hasMore(st *packstream.Structure) bool {
+	if len(st.Fields) == 0 {
+		return false
+	}
+	if m, ok := st.Fields[0].(map[string]interface{}); ok {
+		more, _ := m["has_more"].(bool)
+		return more
+	}
+	return false
+}
+
 // hydrateSummary reads a packstream structure to fill the Cypher query summary.
 func (r *statementResult) hydrateSummary(st *packstream.Structure) error {
 	var (
@@ -92,5 +324,7 @@ func (r *statementResult) hydrateSummary(st *packstream.Structure) error {
 			r.Profile = m
 		}
 	}
+	r.Notifications = parseNotifications(m["notifications"])
+	r.Counters = parseCounters(m["stats"])
 	return nil
 }
@@ -1,6 +1,8 @@
 package neoql
 
 import (
+	"bytes"
+	"context"
 	"database/sql/driver"
 	"gopkg.in/packstream.v1"
 	"io"
@@ -9,16 +11,46 @@ import (
 )
 
 func TestResult_LastInsertId(t *testing.T) {
-	res := new(result)
+	res := new(Result)
 	if _, err := res.LastInsertId(); err == nil {
 		t.Error("LastInsertId should return an error when it is not supported.")
 	}
 }
 
 func TestResult_RowsAffected(t *testing.T) {
-	res := new(result)
-	if _, err := res.RowsAffected(); err == nil {
-		t.Error("RowsAffected should return an error when it is not supported.")
+	for _, test := range []struct {
+		counters Counters
+		expected int64
+	}{
+		{Counters{NodesCreated: 1, PropertiesSet: 1, LabelsAdded: 1}, 1},
+		{Counters{NodesCreated: 2, RelationshipsCreated: 1, PropertiesSet: 4}, 3},
+		{Counters{NodesDeleted: 1, RelationshipsDeleted: 2}, 3},
+		{Counters{PropertiesSet: 4, LabelsAdded: 1}, 4},
+		{Counters{LabelsAdded: 2, LabelsRemoved: 1}, 3},
+		{Counters{IndexesAdded: 1, SystemUpdates: 1}, 0},
+	} {
+		if n, err := (&Result{Counters: test.counters}).RowsAffected(); err != nil {
+			t.Error(err)
+		} else if n != test.expected {
+			t.Errorf("invalid rows affected of %+v, expected %v got %v.", test.counters, test.expected, n)
+		}
+	}
+}
+
+func TestParseCounters(t *testing.T) {
+	c := parseCounters(map[string]interface{}{"nodes-deleted": int64(3), "labels-added": int64(1), "system-updates": int64(2)})
+	expected := Counters{NodesDeleted: 3, LabelsAdded: 1, SystemUpdates: 2, ContainsUpdates: true, ContainsSystemUpdates: true}
+	if c != expected {
+		t.Errorf("invalid counters, expected %+v got %+v.", expected, c)
+	}
+
+	// Since Bolt v5, the server sends the flags.
+	c = parseCounters(map[string]interface{}{"contains-updates": false, "contains-system-updates": true})
+	if c != (Counters{ContainsSystemUpdates: true}) {
+		t.Errorf("invalid counters, got %+v.", c)
+	}
+	if c = parseCounters(nil); c != (Counters{}) {
+		t.Errorf("counters should be zero without stats, got %+v.", c)
 	}
 }
 
@@ -89,9 +121,11 @@ func TestStatementResult_hydrateSummary(t *testing.T) {
 	tp := "The_Type"
 
 	if err := stmt.hydrateSummary(packstream.NewStructure(0, map[string]interface{}{
-		"type":    tp,
-		"plan":    map[string]interface{}{"plan": 42},
-		"profile": map[string]interface{}{"profile": 43}})); err != nil {
+		"type":          tp,
+		"plan":          map[string]interface{}{"plan": 42},
+		"profile":       map[string]interface{}{"profile": 43},
+		"stats":         map[string]interface{}{"nodes-created": int64(1)},
+		"notifications": []interface{}{map[string]interface{}{"code": "Neo.ClientNotification.Statement.CartesianProduct"}}})); err != nil {
 		t.Error(err)
 	} else if stmt.Type != tp {
 		t.Errorf("invalid type, got %v expected %v.", stmt.Type, tp)
@@ -107,6 +141,10 @@ func TestStatementResult_hydrateSummary(t *testing.T) {
 		t.Error("invalid profile map, should contains key 'profile'.")
 	} else if profile != 43 {
 		t.Errorf("invalid profile value, got %v expected %v.", profile, 43)
+	} else if len(stmt.Notifications) != 1 {
+		t.Errorf("invalid notifications, got %v expected 1 notification.", stmt.Notifications)
+	} else if stmt.Counters.NodesCreated != 1 {
+		t.Errorf("invalid counters, got %+v expected 1 node created.", stmt.Counters)
 	}
 
 	// Failures
@@ -123,3 +161,92 @@ func TestStatementResult_hydrateSummary(t *testing.T) {
 		t.Errorf("error should be nil on empty map, got %v.", err)
 	}
 }
+
+func TestStatementResult_stream(t *testing.T) {
+	var (
+		wr bytes.Buffer
+		rd bytes.Buffer
+	)
+	c := testMockConn(t, &rd, &wr)
+	defer c.Close()
+	c.version = 0x0404
+	c.fetchSize = 2
+	dst := make([]driver.Value, 1)
+
+	// Records are pulled by batches
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{"a"}})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteRecord, []interface{}{int64(1)})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteRecord, []interface{}{int64(2)})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"has_more": true})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteRecord, []interface{}{int64(3)})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"type": "r"})))
+	data := testGetEncodedMessage(t, packstream.NewStructure(byteRun, "RETURN 1", map[string]interface{}{}, map[string]interface{}{}))
+	data = append(data, testGetEncodedMessage(t, packstream.NewStructure(bytePull, map[string]interface{}{"n": int64(2)}))...)
+	data = append(data, testGetEncodedMessage(t, packstream.NewStructure(bytePull, map[string]interface{}{"n": int64(2)}))...)
+	res, err := c.run(context.Background(), "RETURN 1", map[string]interface{}{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := int64(1); i <= 3; i++ {
+		if err = res.Next(dst); err != nil {
+			t.Fatal(err)
+		} else if dst[0] != i {
+			t.Errorf("invalid value, expected %v got %v.", i, dst[0])
+		}
+	}
+	if err = res.Next(dst); err != io.EOF {
+		t.Errorf("error should be EOF at the end of the stream, got %v.", err)
+	} else if res.Type != "r" {
+		t.Errorf("invalid summary type, expected %v got %v.", "r", res.Type)
+	} else if !bytes.Equal(wr.Bytes(), data) {
+		t.Errorf("unexpected messages, expected %# x got %# x.", data, wr.Bytes())
+	} else if c.state != stateReady {
+		t.Errorf("connection state should be %v, got %v.", stateReady, c.state)
+	}
+
+	// Remaining records are discarded when closing the result
+	wr.Reset()
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{"a"}})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteRecord, []interface{}{int64(1)})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteRecord, []interface{}{int64(2)})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"has_more": true})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	data = testGetEncodedMessage(t, packstream.NewStructure(byteDiscard, map[string]interface{}{"n": int64(-1)}))
+	if res, err = c.run(context.Background(), "RETURN 1", map[string]interface{}{}, false); err != nil {
+		t.Fatal(err)
+	} else if err = res.Next(dst); err != nil {
+		t.Error(err)
+	} else if err = res.Close(); err != nil {
+		t.Error(err)
+	} else if !bytes.HasSuffix(wr.Bytes(), data) {
+		t.Errorf("last message should be a discard, expected %# x got %# x.", data, wr.Bytes())
+	} else if c.state != stateReady {
+		t.Errorf("connection state should be %v, got %v.", stateReady, c.state)
+	} else if rd.Len() != 0 {
+		t.Errorf("all responses should have been read, %v bytes remaining.", rd.Len())
+	}
+
+	// Remaining records are kept in memory when another query is run
+	c.version = 1
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{"a"}})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteRecord, []interface{}{int64(1)})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteRecord, []interface{}{int64(2)})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{"b"}})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	if res, err = c.run(context.Background(), "RETURN 1", map[string]interface{}{}, false); err != nil {
+		t.Fatal(err)
+	} else if err = res.Next(dst); err != nil {
+		t.Error(err)
+	} else if _, err = c.exec(context.Background(), "RETURN 2", map[string]interface{}{}); err != nil {
+		t.Error(err)
+	} else if len(res.Rows) != 1 {
+		t.Errorf("expected %v record kept in memory, got %v.", 1, len(res.Rows))
+	} else if err = res.Next(dst); err != nil {
+		t.Error(err)
+	} else if dst[0] != int64(2) {
+		t.Errorf("invalid value, expected %v got %v.", 2, dst[0])
+	} else if err = res.Next(dst); err != io.EOF {
+		t.Errorf("error should be EOF at the end of the stream, got %v.", err)
+	}
+}
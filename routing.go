@@ -0,0 +1,249 @@
+package neoql
+
+import (
+	"context"
+	"errors"
+	"gopkg.in/neoql.v1/types"
+	"gopkg.in/packstream.v1"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	accessModeRead  = "read"  // Access mode of the connections to the cluster readers.
+	accessModeWrite = "write" // Access mode of the connections to the cluster writers, the default one.
+)
+
+// ErrNoServer is returned when the routing table of a cluster does not list any server for the access mode.
+var ErrNoServer = errors.New("open: No server available in the routing table for the access mode")
+
+// routingTable is the routing table of a Neo4j cluster database, which lists the servers able to route, to read or to
+// write. A routingTable is safe for concurrent use.
+type routingTable struct {
+	mu      sync.Mutex
+	routers []string
+	readers []string
+	writers []string
+	expires time.Time
+	next    int // next is incremented each time a server is picked, so the connections are spread over the servers.
+}
+
+// expired returns true if the table must be fetched again from the cluster: its time to live elapsed, or it no longer
+// lists any router, or any server for the "mode" access mode. The mutex must be held by the caller.
+func (t *routingTable) expired(mode string) bool {
+	return time.Now().After(t.expires) || len(t.routers) == 0 || len(t.servers(mode)) == 0
+}
+
+// servers returns the servers for the "mode" access mode. The mutex must be held by the caller.
+func (t *routingTable) servers(mode string) []string {
+	if mode == accessModeRead {
+		return t.readers
+	}
+	return t.writers
+}
+
+// server returns the address of a server for the "mode" access mode, the servers being picked in turn.
+func (t *routingTable) server(mode string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	servers := t.servers(mode)
+	if len(servers) == 0 {
+		return "", ErrNoServer
+	}
+	t.next++
+	return servers[t.next%len(servers)], nil
+}
+
+// update replaces the servers of the table by the ones of the "fetched" table. The mutex must be held by the caller.
+func (t *routingTable) update(fetched *routingTable) {
+	t.routers = fetched.routers
+	t.readers = fetched.readers
+	t.writers = fetched.writers
+	t.expires = fetched.expires
+}
+
+// forget removes the "address" server from the table, once it is unreachable.
+func (t *routingTable) forget(address string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.routers = removeString(t.routers, address)
+	t.readers = removeString(t.readers, address)
+	t.writers = removeString(t.writers, address)
+}
+
+// forgetWriter removes the "address" server from the writers of the table, once it is no longer the leader.
+func (t *routingTable) forgetWriter(address string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.writers = removeString(t.writers, address)
+}
+
+// removeString returns a copy of the "list" slice without "s".
+func removeString(list []string, s string) []string {
+	kept := make([]string, 0, len(list))
+	for _, item := range list {
+		if item != s {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// routingTable returns the routing table of the database selected by the "c" connector, for the cluster reached
+// through its URL host. The table is cached until it expires for the connector access mode, it is then fetched again
+// from one of its routers, or from the URL host, waiting for the connector bookmarks.
+func (d *neoDriver) routingTable(ctx context.Context, c *connector) (*routingTable, error) {
+	key := c.url.Host + "/" + c.database
+	d.mu.Lock()
+	if d.tables == nil {
+		d.tables = make(map[string]*routingTable)
+	}
+	table, ok := d.tables[key]
+	if !ok {
+		table = new(routingTable)
+		d.tables[key] = table
+	}
+	d.mu.Unlock()
+
+	table.mu.Lock()
+	defer table.mu.Unlock()
+	if !table.expired(c.mode) {
+		return table, nil
+	}
+	var err error
+	for _, address := range append(append([]string(nil), table.routers...), c.url.Host) {
+		var fetched *routingTable
+		if fetched, err = c.fetchRoutingTable(ctx, address); err == nil {
+			table.update(fetched)
+			return table, nil
+		}
+	}
+	return nil, err
+}
+
+// fetchRoutingTable opens a connection to the "address" router, and fetches the routing table of the database
+// selected by the connector.
+func (c *connector) fetchRoutingTable(ctx context.Context, address string) (*routingTable, error) {
+	cn, err := c.connect(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	defer cn.Close()
+	cn.bookmarks = c.bookmarks
+	return cn.route(routingContext(c.url), c.database)
+}
+
+// routingContext returns the routing context sent to the cluster, which is made of the URL query parameters, except
+// the ones of the driver, and of the address the cluster is reached through.
+func routingContext(u *url.URL) map[string]interface{} {
+	routing := map[string]interface{}{"address": u.Host}
+	for k, v := range u.Query() {
+		if !dsnParameters[k] && len(v) != 0 {
+			routing[k] = v[0]
+		}
+	}
+	return routing
+}
+
+// route fetches the routing table of the "database" database, the default one if empty, "routing" being the
+// routing context. Since Bolt v4.3, the table is fetched with a ROUTE message, before with a procedure.
+func (c *conn) route(routing map[string]interface{}, database string) (*routingTable, error) {
+	bookmarks := []string{}
+	if c.bookmarks != nil {
+		bookmarks = append(bookmarks, c.bookmarks.Bookmarks()...)
+	}
+
+	if c.atLeast(4, 3) {
+		var db interface{}
+		if database != "" {
+			db = database
+		}
+		msg := packstream.NewStructure(byteRoute, routing, bookmarks, db)
+		if c.atLeast(4, 4) {
+			extra := map[string]interface{}{}
+			if database != "" {
+				extra["db"] = database
+			}
+			msg = packstream.NewStructure(byteRoute, routing, bookmarks, extra)
+		}
+		res, err := c.request(msg)
+		if err != nil {
+			return nil, err
+		} else if res.Signature != byteSuccess {
+			return nil, messageError(res, types.ErrProtocol)
+		} else if len(res.Fields) == 0 {
+			return nil, types.ErrProtocol
+		} else if m, ok := res.Fields[0].(map[string]interface{}); !ok {
+			return nil, types.ErrProtocol
+		} else if rt, ok := m["rt"].(map[string]interface{}); !ok {
+			return nil, types.ErrProtocol
+		} else {
+			return parseRoutingTable(rt["ttl"], rt["servers"])
+		}
+	}
+
+	query := "CALL dbms.cluster.routing.getRoutingTable($context)"
+	params := map[string]interface{}{"context": routing}
+	if c.atLeast(4, 0) {
+		// The procedure is run against the system database, since the requested database may be unavailable.
+		query = "CALL dbms.routing.getRoutingTable($context, $database)"
+		params["database"] = nil
+		if database != "" {
+			params["database"] = database
+		}
+		c.database = "system"
+	}
+	result, err := c.run(context.Background(), query, params, false)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+	if record, err := result.fetch(false); err != nil {
+		return nil, err
+	} else if len(record) != 2 {
+		return nil, types.ErrProtocol
+	} else {
+		return parseRoutingTable(record[0], record[1])
+	}
+}
+
+// parseRoutingTable returns the routing table made of the "ttl" time to live, in seconds, and of the "servers" list
+// returned by the cluster.
+func parseRoutingTable(ttl, servers interface{}) (*routingTable, error) {
+	seconds, ok := ttl.(int64)
+	if !ok {
+		return nil, types.ErrProtocol
+	}
+	list, ok := servers.([]interface{})
+	if !ok {
+		return nil, types.ErrProtocol
+	}
+
+	table := &routingTable{expires: time.Now().Add(time.Duration(seconds) * time.Second)}
+	for _, item := range list {
+		server, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, types.ErrProtocol
+		}
+		addresses, ok := server["addresses"].([]interface{})
+		if !ok {
+			return nil, types.ErrProtocol
+		}
+		for _, address := range addresses {
+			s, ok := address.(string)
+			if !ok {
+				return nil, types.ErrProtocol
+			}
+			switch server["role"] {
+			case "ROUTE":
+				table.routers = append(table.routers, s)
+			case "READ":
+				table.readers = append(table.readers, s)
+			case "WRITE":
+				table.writers = append(table.writers, s)
+			}
+		}
+	}
+	return table, nil
+}
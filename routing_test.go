@@ -0,0 +1,137 @@
+package neoql
+
+import (
+	"bytes"
+	"context"
+	"gopkg.in/neoql.v1/types"
+	"gopkg.in/packstream.v1"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testRoutingServers returns a servers list, as returned by a cluster, for testing purposes.
+func testRoutingServers() []interface{} {
+	return []interface{}{
+		map[string]interface{}{"addresses": []interface{}{"a:7687", "b:7687"}, "role": "ROUTE"},
+		map[string]interface{}{"addresses": []interface{}{"b:7687", "c:7687"}, "role": "READ"},
+		map[string]interface{}{"addresses": []interface{}{"a:7687"}, "role": "WRITE"},
+	}
+}
+
+func TestParseRoutingTable(t *testing.T) {
+	if table, err := parseRoutingTable(int64(300), testRoutingServers()); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(table.routers, []string{"a:7687", "b:7687"}) {
+		t.Errorf("invalid routers, got %v.", table.routers)
+	} else if !reflect.DeepEqual(table.readers, []string{"b:7687", "c:7687"}) {
+		t.Errorf("invalid readers, got %v.", table.readers)
+	} else if !reflect.DeepEqual(table.writers, []string{"a:7687"}) {
+		t.Errorf("invalid writers, got %v.", table.writers)
+	} else if table.expires.Before(time.Now().Add(299 * time.Second)) {
+		t.Errorf("invalid expiration time, got %v.", table.expires)
+	}
+
+	if _, err := parseRoutingTable("300", testRoutingServers()); err != types.ErrProtocol {
+		t.Errorf("error should be %v on invalid time to live, got %v.", types.ErrProtocol, err)
+	} else if _, err := parseRoutingTable(int64(300), []interface{}{42}); err != types.ErrProtocol {
+		t.Errorf("error should be %v on invalid servers, got %v.", types.ErrProtocol, err)
+	}
+}
+
+func TestRoutingTable(t *testing.T) {
+	table, _ := parseRoutingTable(int64(300), testRoutingServers())
+	if table.expired(accessModeRead) || table.expired(accessModeWrite) {
+		t.Error("table should not be expired.")
+	}
+	first, _ := table.server(accessModeRead)
+	second, _ := table.server(accessModeRead)
+	if first == second {
+		t.Errorf("readers should be picked in turn, got %v twice.", first)
+	}
+
+	table.forget("b:7687")
+	if !reflect.DeepEqual(table.routers, []string{"a:7687"}) || !reflect.DeepEqual(table.readers, []string{"c:7687"}) {
+		t.Errorf("unreachable server should be removed, got %v and %v.", table.routers, table.readers)
+	}
+	table.forgetWriter("a:7687")
+	if !table.expired(accessModeWrite) {
+		t.Error("table should be expired without any writer.")
+	} else if table.expired(accessModeRead) {
+		t.Error("table should not be expired for readers.")
+	} else if _, err := table.server(accessModeWrite); err != ErrNoServer {
+		t.Errorf("error should be %v without any writer, got %v.", ErrNoServer, err)
+	}
+
+	table.expires = time.Now().Add(-time.Second)
+	if !table.expired(accessModeRead) {
+		t.Error("table should be expired once its time to live elapsed.")
+	}
+}
+
+func TestRoutingContext(t *testing.T) {
+	u, _ := url.Parse("neo4j://user:pass@a:7687?database=sales&region=eu")
+	expected := map[string]interface{}{"address": "a:7687", "region": "eu"}
+	if context := routingContext(u); !reflect.DeepEqual(context, expected) {
+		t.Errorf("invalid routing context, expected %v got %v.", expected, context)
+	}
+}
+
+func TestConn_route(t *testing.T) {
+	var (
+		wr bytes.Buffer
+		rd bytes.Buffer
+	)
+	c := testMockConn(t, &rd, &wr)
+	defer c.Close()
+	context := map[string]interface{}{"address": "a:7687"}
+
+	// Bolt v4.4 sends a ROUTE message.
+	c.version = 0x0404
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{
+		"rt": map[string]interface{}{"ttl": int64(300), "db": "sales", "servers": testRoutingServers()},
+	})))
+	data := testGetEncodedMessage(t, packstream.NewStructure(byteRoute, context, []string{}, map[string]interface{}{"db": "sales"}))
+	if table, err := c.route(context, "sales"); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(wr.Bytes(), data) {
+		t.Errorf("unexpected route message, expected %# x got %# x.", data, wr.Bytes())
+	} else if !reflect.DeepEqual(table.writers, []string{"a:7687"}) {
+		t.Errorf("invalid writers, got %v.", table.writers)
+	}
+
+	// Bolt v3 calls a procedure.
+	wr.Reset()
+	c.version = 3
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{"ttl", "servers"}})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteRecord, []interface{}{int64(300), testRoutingServers()})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	if table, err := c.route(context, ""); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(table.readers, []string{"b:7687", "c:7687"}) {
+		t.Errorf("invalid readers, got %v.", table.readers)
+	} else if rd.Len() != 0 {
+		t.Errorf("all responses should have been read, %v bytes remaining.", rd.Len())
+	}
+}
+
+func TestConn_notALeader(t *testing.T) {
+	var (
+		wr bytes.Buffer
+		rd bytes.Buffer
+	)
+	c := testMockConn(t, &rd, &wr)
+	defer c.Close()
+	c.table, _ = parseRoutingTable(int64(300), testRoutingServers())
+	c.address = "a:7687"
+
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteFailure, map[string]interface{}{"code": notALeaderCode, "message": "no"})))
+	if _, err := c.run(context.Background(), "CREATE (n)", map[string]interface{}{}, true); err == nil {
+		t.Error("error should not be nil when the server is not the leader.")
+	} else if c.state != stateDefunct {
+		t.Errorf("state should be %v, got %v.", stateDefunct, c.state)
+	} else if len(c.table.writers) != 0 {
+		t.Errorf("server should be removed from the writers, got %v.", c.table.writers)
+	}
+}
@@ -0,0 +1,105 @@
+package neoql
+
+import (
+	"context"
+	"io"
+	"strings"
+)
+
+// scriptRows implements the sql/driver.Rows and sql/driver.RowsNextResultSet interfaces for the result of a script.
+// The result of each statement is a result set, the next statement being run once the previous result is closed.
+type scriptRows struct {
+	*statementResult
+	conn       *conn
+	ctx        context.Context
+	params     map[string]interface{}
+	statements []string // statements which have not been run yet.
+}
+
+// splitScript splits the "script" Cypher script into its statements, separated by semicolons. The semicolons within
+// the string literals, the quoted identifiers and the comments are not separators. The statements made of spaces and
+// comments only are skipped.
+func splitScript(script string) []string {
+	var (
+		statements []string
+		start      int
+		offset     int
+		empty      = true
+	)
+
+	for _, t := range tokenize(script) {
+		if t.kind == tokenPunct && t.text == ";" {
+			if !empty {
+				statements = append(statements, strings.TrimSpace(script[start:offset]))
+			}
+			start, empty = offset+1, true
+		} else if t.significant() {
+			empty = false
+		}
+		offset += len(t.text)
+	}
+	if !empty {
+		statements = append(statements, strings.TrimSpace(script[start:]))
+	}
+	return statements
+}
+
+// scriptStatements returns the statements of the "query" Cypher query if ctx carries the script flag, nil otherwise.
+func scriptStatements(ctx context.Context, query string) []string {
+	if script, _ := ctx.Value(scriptKey).(bool); !script {
+		return nil
+	}
+	return splitScript(query)
+}
+
+// runScript runs the first of the "statements" Cypher queries with the "params" parameters, and returns its result,
+// the following statements being run by NextResultSet.
+func (c *conn) runScript(ctx context.Context, statements []string, params map[string]interface{}) (*scriptRows, error) {
+	res, err := c.run(ctx, statements[0], params, false)
+	if err != nil {
+		return nil, err
+	}
+	return &scriptRows{statementResult: res, conn: c, ctx: ctx, params: params, statements: statements[1:]}, nil
+}
+
+// execScript runs the "statements" Cypher queries in order with the "params" parameters, the server discarding their
+// records, and returns the sum of their update counters. It stops at the first statement which fails.
+func (c *conn) execScript(ctx context.Context, statements []string, params map[string]interface{}) (Counters, error) {
+	var counters Counters
+
+	for _, statement := range statements {
+		res, err := c.exec(ctx, statement, params)
+		if err != nil {
+			return counters, err
+		}
+		counters.add(res.Counters)
+	}
+	return counters, nil
+}
+
+// HasNextResultSet implements the HasNextResultSet() method of the sql/driver.RowsNextResultSet interface.
+func (r *scriptRows) HasNextResultSet() bool {
+	return len(r.statements) != 0
+}
+
+// NextResultSet implements the NextResultSet() method of the sql/driver.RowsNextResultSet interface.
+// The records of the current statement which have not been read are discarded, then the next statement is run. If it
+// fails, the statements following it are not run.
+func (r *scriptRows) NextResultSet() error {
+	if len(r.statements) == 0 {
+		return io.EOF
+	}
+	statement := r.statements[0]
+	r.statements = r.statements[1:]
+	if err := r.statementResult.Close(); err != nil {
+		r.statements = nil
+		return err
+	}
+	res, err := r.conn.run(r.ctx, statement, r.params, false)
+	if err != nil {
+		r.statements = nil
+		return err
+	}
+	r.statementResult = res
+	return nil
+}
@@ -0,0 +1,104 @@
+package neoql
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"gopkg.in/packstream.v1"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestSplitScript(t *testing.T) {
+	for _, test := range []struct {
+		script   string
+		expected []string
+	}{
+		{"RETURN 1", []string{"RETURN 1"}},
+		{"CREATE (n);\nMATCH (n) RETURN n;\n", []string{"CREATE (n)", "MATCH (n) RETURN n"}},
+		{"RETURN ';' AS a; RETURN `;` // ;\n; /* ; */", []string{"RETURN ';' AS a", "RETURN `;` // ;"}},
+		{" ; ;", nil},
+	} {
+		if statements := splitScript(test.script); !reflect.DeepEqual(statements, test.expected) {
+			t.Errorf("invalid statements of %q, expected %q got %q.", test.script, test.expected, statements)
+		}
+	}
+
+	if statements := scriptStatements(context.Background(), "RETURN 1; RETURN 2"); statements != nil {
+		t.Errorf("a query should not be split without the script flag, got %q.", statements)
+	}
+}
+
+func TestConn_QueryContext_script(t *testing.T) {
+	var (
+		wr bytes.Buffer
+		rd bytes.Buffer
+	)
+	c := testMockConn(t, &rd, &wr)
+	defer c.Close()
+	ctx := WithScript(context.Background())
+	dst := make([]driver.Value, 1)
+
+	// The second statement runs once the first result set is done.
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{"a"}})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteRecord, []interface{}{int64(1)})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{"b"}})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteRecord, []interface{}{int64(2)})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	rows, err := c.QueryContext(ctx, "RETURN 1 AS a; RETURN 2 AS b", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	script, ok := rows.(driver.RowsNextResultSet)
+	if !ok {
+		t.Fatal("the rows of a script should implement driver.RowsNextResultSet.")
+	}
+	if err = script.Next(dst); err != nil {
+		t.Error(err)
+	} else if dst[0] != int64(1) {
+		t.Errorf("invalid record, expected %v got %v.", 1, dst[0])
+	} else if !script.HasNextResultSet() {
+		t.Error("the script should have a next result set.")
+	} else if err = script.NextResultSet(); err != nil {
+		t.Error(err)
+	} else if columns := script.Columns(); !reflect.DeepEqual(columns, []string{"b"}) {
+		t.Errorf("invalid columns, expected [b] got %v.", columns)
+	} else if err = script.Next(dst); err != nil {
+		t.Error(err)
+	} else if dst[0] != int64(2) {
+		t.Errorf("invalid record, expected %v got %v.", 2, dst[0])
+	} else if script.HasNextResultSet() {
+		t.Error("the script should not have a next result set.")
+	} else if err = script.NextResultSet(); err != io.EOF {
+		t.Errorf("error should be %v, got %v.", io.EOF, err)
+	}
+}
+
+func TestConn_ExecContext_script(t *testing.T) {
+	var (
+		wr bytes.Buffer
+		rd bytes.Buffer
+	)
+	c := testMockConn(t, &rd, &wr)
+	defer c.Close()
+
+	// The statements run in order, and their counters are summed.
+	for i := 0; i < 2; i++ {
+		rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{}})))
+		rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"stats": map[string]interface{}{"nodes-created": int64(1)}})))
+	}
+	data := testGetEncodedMessage(t, c.runMessage(context.Background(), "CREATE (:A)", map[string]interface{}{}))
+	data = append(data, testGetEncodedMessage(t, c.discardMessage(-1, -1))...)
+	data = append(data, testGetEncodedMessage(t, c.runMessage(context.Background(), "CREATE (:B)", map[string]interface{}{}))...)
+	data = append(data, testGetEncodedMessage(t, c.discardMessage(-1, -1))...)
+	if res, err := c.ExecContext(WithScript(context.Background()), "CREATE (:A);\nCREATE (:B);", nil); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(wr.Bytes(), data) {
+		t.Errorf("unexpected messages, expected %# x got %# x.", data, wr.Bytes())
+	} else if n, _ := res.RowsAffected(); n != 2 {
+		t.Errorf("invalid rows affected, expected %v got %v.", 2, n)
+	}
+}
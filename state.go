@@ -0,0 +1,142 @@
+package neoql
+
+import (
+	"fmt"
+	"gopkg.in/packstream.v1"
+)
+
+// connState is the state of a connection, as seen by the Neo4j server.
+type connState int
+
+const (
+	stateConnected      connState = iota // The connection is opened, but not initialized yet.
+	stateAuthentication                  // The connection is initialized, but not authenticated yet (Bolt v5.1+).
+	stateReady                           // The connection is ready to run a query or to begin a transaction.
+	stateStreaming                       // A query has been run, its result can be pulled or discarded.
+	stateTxReady                         // A transaction is opened, it is ready to run a query (Bolt v3+).
+	stateTxStreaming                     // A query has been run within a transaction (Bolt v3+).
+	stateFailed                          // A request failed, the following ones are ignored until acknowledgement.
+	stateInterrupted                     // A RESET message has been sent, the pending requests are ignored.
+	stateDefunct                         // The connection is no longer usable.
+)
+
+// stateNames are the names of the connection states, as defined by the Bolt protocol.
+var stateNames = map[connState]string{
+	stateConnected:      "CONNECTED",
+	stateAuthentication: "AUTHENTICATION",
+	stateReady:          "READY",
+	stateStreaming:      "STREAMING",
+	stateTxReady:        "TX_READY",
+	stateTxStreaming:    "TX_STREAMING",
+	stateFailed:         "FAILED",
+	stateInterrupted:    "INTERRUPTED",
+	stateDefunct:        "DEFUNCT",
+}
+
+// String implements the fmt.Stringer interface.
+func (s connState) String() string {
+	if name, ok := stateNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", int(s))
+}
+
+// nextState returns the state of the connection once the server successfully processed the message "signature",
+// sent while the connection is in its current state. Because requests may be sent before the previous responses are
+// read, the state is updated when the message is sent, as if it succeeded, and corrected when a response is read.
+// It returns an error if the message is not allowed in the current state.
+func (c *conn) nextState(signature byte) (connState, error) {
+	switch {
+	case signature == byteGoodbye && c.atLeast(3, 0):
+		return stateDefunct, nil
+	case signature == byteReset:
+		return stateInterrupted, nil
+	}
+
+	switch c.state {
+	case stateConnected:
+		if signature == byteHello {
+			if c.atLeast(5, 1) {
+				return stateAuthentication, nil
+			}
+			return stateReady, nil
+		}
+	case stateAuthentication:
+		if signature == byteLogon {
+			return stateReady, nil
+		}
+	case stateReady:
+		switch {
+		case signature == byteRun:
+			return stateStreaming, nil
+		case signature == byteBegin && c.atLeast(3, 0):
+			return stateTxReady, nil
+		case signature == byteLogoff && c.atLeast(5, 1):
+			return stateAuthentication, nil
+		case signature == byteRoute && c.atLeast(4, 3):
+			return stateReady, nil
+		}
+	case stateStreaming:
+		if signature == bytePull || signature == byteDiscard {
+			return stateReady, nil
+		}
+	case stateTxReady:
+		switch signature {
+		case byteRun:
+			return stateTxStreaming, nil
+		case byteCommit, byteRollback:
+			return stateReady, nil
+		}
+	case stateTxStreaming:
+		switch {
+		case signature == bytePull || signature == byteDiscard:
+			return stateTxReady, nil
+		case signature == byteRun && c.atLeast(4, 0):
+			return stateTxStreaming, nil
+		}
+	case stateFailed:
+		if signature == byteAckFailure && !c.atLeast(3, 0) {
+			return stateReady, nil
+		}
+	}
+	return c.state, fmt.Errorf("neoql: message 0x%02X cannot be sent in %v state", signature, c.state)
+}
+
+// received updates the connection state once a response is read from the server.
+// The summary messages, which are SUCCESS, FAILURE and IGNORED, are the responses to the pending requests, in the
+// same order the requests have been sent.
+func (c *conn) received(st *packstream.Structure) {
+	var request byte
+
+	switch st.Signature {
+	case byteSuccess, byteFailure, byteIgnored:
+	default:
+		return
+	}
+	if len(c.inflight) == 0 {
+		return
+	}
+	request, c.inflight = c.inflight[0], c.inflight[1:]
+
+	switch st.Signature {
+	case byteSuccess:
+		switch {
+		case request == byteReset || request == byteAckFailure:
+			c.state = stateReady
+		case (request == bytePull || request == byteDiscard) && hasMore(st):
+			// The state was updated as if all the records were pulled.
+			if c.state == stateReady {
+				c.state = stateStreaming
+			} else if c.state == stateTxReady {
+				c.state = stateTxStreaming
+			}
+		}
+	case byteFailure:
+		switch request {
+		case byteHello, byteLogon, byteReset, byteAckFailure:
+			c.state = stateDefunct
+		default:
+			c.state = stateFailed
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package neoql
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"gopkg.in/packstream.v1"
+	"testing"
+)
+
+func TestConnState_String(t *testing.T) {
+	if s := stateTxStreaming.String(); s != "TX_STREAMING" {
+		t.Errorf("invalid state name, expected %v got %v.", "TX_STREAMING", s)
+	}
+	if s := connState(42).String(); s != "UNKNOWN(42)" {
+		t.Errorf("invalid state name, expected %v got %v.", "UNKNOWN(42)", s)
+	}
+}
+
+func TestConn_nextState(t *testing.T) {
+	tests := []struct {
+		version   uint32
+		state     connState
+		signature byte
+		next      connState
+		valid     bool
+	}{
+		{1, stateConnected, byteInit, stateReady, true},
+		{0x0105, stateConnected, byteHello, stateAuthentication, true},
+		{0x0105, stateAuthentication, byteLogon, stateReady, true},
+		{0x0105, stateReady, byteLogoff, stateAuthentication, true},
+		{0x0004, stateReady, byteLogoff, stateReady, false},
+		{1, stateReady, byteRun, stateStreaming, true},
+		{1, stateReady, byteBegin, stateReady, false},
+		{3, stateReady, byteBegin, stateTxReady, true},
+		{1, stateStreaming, bytePullAll, stateReady, true},
+		{1, stateStreaming, byteRun, stateStreaming, false},
+		{3, stateTxReady, byteRun, stateTxStreaming, true},
+		{3, stateTxReady, byteCommit, stateReady, true},
+		{3, stateTxReady, byteRollback, stateReady, true},
+		{3, stateTxStreaming, byteDiscardAll, stateTxReady, true},
+		{3, stateTxStreaming, byteRun, stateTxStreaming, false},
+		{0x0004, stateTxStreaming, byteRun, stateTxStreaming, true},
+		{3, stateTxStreaming, byteCommit, stateTxStreaming, false},
+		{1, stateFailed, byteRun, stateFailed, false},
+		{1, stateFailed, byteAckFailure, stateReady, true},
+		{3, stateFailed, byteAckFailure, stateFailed, false},
+		{3, stateFailed, byteReset, stateInterrupted, true},
+		{3, stateStreaming, byteGoodbye, stateDefunct, true},
+		{1, stateReady, byteGoodbye, stateReady, false},
+	}
+
+	c := new(conn)
+	for _, test := range tests {
+		c.version = test.version
+		c.state = test.state
+		if next, err := c.nextState(test.signature); test.valid && err != nil {
+			t.Errorf("message 0x%02X should be allowed in state %v, got %v.", test.signature, test.state, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("message 0x%02X should not be allowed in state %v.", test.signature, test.state)
+		} else if next != test.next {
+			t.Errorf("invalid state after message 0x%02X in state %v, expected %v got %v.", test.signature, test.state, test.next, next)
+		}
+	}
+}
+
+func TestConn_received(t *testing.T) {
+	c := new(conn)
+	c.version = 3
+
+	c.state = stateReady
+	c.inflight = []byte{byteRun, bytePullAll}
+	c.received(packstream.NewStructure(byteRecord, []interface{}{}))
+	if len(c.inflight) != 2 {
+		t.Errorf("a record should not be a response to a request, %v requests pending.", len(c.inflight))
+	}
+	c.received(packstream.NewStructure(byteFailure, map[string]interface{}{}))
+	if c.state != stateFailed {
+		t.Errorf("state should be %v after a failure, got %v.", stateFailed, c.state)
+	}
+	c.received(packstream.NewStructure(byteIgnored))
+	if c.state != stateFailed {
+		t.Errorf("state should be %v after an ignored response, got %v.", stateFailed, c.state)
+	} else if len(c.inflight) != 0 {
+		t.Errorf("no more request should be pending, got %v.", len(c.inflight))
+	}
+
+	c.state = stateInterrupted
+	c.inflight = []byte{byteReset}
+	c.received(packstream.NewStructure(byteSuccess, map[string]interface{}{}))
+	if c.state != stateReady {
+		t.Errorf("state should be %v after a successful reset, got %v.", stateReady, c.state)
+	}
+
+	c.state = stateConnected
+	c.inflight = []byte{byteHello}
+	c.received(packstream.NewStructure(byteFailure, map[string]interface{}{}))
+	if c.state != stateDefunct {
+		t.Errorf("state should be %v after a failed initialization, got %v.", stateDefunct, c.state)
+	}
+}
+
+func TestConn_recoverFailure(t *testing.T) {
+	var (
+		wr bytes.Buffer
+		rd bytes.Buffer
+	)
+	c := testMockConn(t, &rd, &wr)
+	defer c.Close()
+
+	if err := c.recoverFailure(); err != nil {
+		t.Error(err)
+	} else if wr.Len() != 0 {
+		t.Errorf("nothing should be sent when the connection is ready, got %# x.", wr.Bytes())
+	}
+
+	// A RUN failure followed by an ignored PULL_ALL, on Bolt v1.
+	c.state = stateReady
+	c.inflight = []byte{byteRun, bytePullAll}
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteFailure, map[string]interface{}{"code": "42", "message": "hello"})))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteIgnored)))
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	data := testGetEncodedMessage(t, packstream.NewStructure(byteAckFailure))
+	if err := c.recoverFailure(); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(wr.Bytes(), data) {
+		t.Errorf("unexpected acknowledgement, expected %# x got %# x.", data, wr.Bytes())
+	} else if c.state != stateReady {
+		t.Errorf("state should be %v, got %v.", stateReady, c.state)
+	} else if rd.Len() != 0 {
+		t.Errorf("all responses should have been read, %v bytes remaining.", rd.Len())
+	}
+
+	// Bolt v3 resets the connection.
+	wr.Reset()
+	c.version = 3
+	c.state = stateFailed
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	data = testGetEncodedMessage(t, packstream.NewStructure(byteReset))
+	if err := c.recoverFailure(); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(wr.Bytes(), data) {
+		t.Errorf("unexpected reset, expected %# x got %# x.", data, wr.Bytes())
+	} else if c.state != stateReady {
+		t.Errorf("state should be %v, got %v.", stateReady, c.state)
+	}
+
+	c.state = stateDefunct
+	if err := c.recoverFailure(); err != driver.ErrBadConn {
+		t.Errorf("error should be %v when connection is defunct, got %v.", driver.ErrBadConn, err)
+	}
+}
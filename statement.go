@@ -1,33 +1,66 @@
 package neoql
 
 import (
+	"context"
 	"database/sql/driver"
+	"fmt"
+	"gopkg.in/neoql.v1/types"
+	"gopkg.in/packstream.v1"
+	"reflect"
 	"strconv"
+	"time"
 )
 
+// PreparedStatement is implemented by the neoql prepared statements, so the result columns of a query validated with
+// EXPLAIN can be read from the statement prepared through the Raw() method of a sql.Conn.
+type PreparedStatement interface {
+	// Columns returns the names of the result columns of the query, as returned by EXPLAIN. It is nil if the query
+	// has not been explained when prepared.
+	Columns() []string
+}
+
 // stmt implements the sql/driver.Stmt interface.
 type stmt struct {
-	conn  *conn
-	query string
+	conn      *conn
+	query     string
+	explained bool     // explained is true if the query has been validated with EXPLAIN when prepared.
+	columns   []string // columns are the result columns returned by EXPLAIN.
+	numInput  int      // numInput is the number of parameters referenced by the explained query.
 }
 
 // Exec implements the Exec() method of the sql/driver.Stmt interface.
+// The records returned by the query, if any, are discarded by the server.
 func (stm *stmt) Exec(args []driver.Value) (driver.Result, error) {
-	if _, err := stm.conn.run(stm.query, makeArgsMap(args)); err != nil {
-		return nil, err
-	}
-	return &result{}, nil
+	return stm.conn.ExecContext(context.Background(), stm.query, valuesToNamedValues(args))
 }
 
 // Query implements the Query() method of the sql/driver.Stmt interface.
 func (stm *stmt) Query(args []driver.Value) (driver.Rows, error) {
-	return stm.conn.run(stm.query, makeArgsMap(args))
+	return stm.conn.QueryContext(context.Background(), stm.query, valuesToNamedValues(args))
+}
+
+// ExecContext implements the ExecContext() method of the sql/driver.StmtExecContext interface.
+func (stm *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return stm.conn.ExecContext(ctx, stm.query, args)
+}
+
+// QueryContext implements the QueryContext() method of the sql/driver.StmtQueryContext interface.
+func (stm *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return stm.conn.QueryContext(ctx, stm.query, args)
 }
 
 // NumInput implements the NumInput() method of the sql/driver.Stmt interface.
-// It is not supported by this driver.
+// It returns the number of parameters referenced by the query if it has been explained when prepared, -1 otherwise.
 func (stm *stmt) NumInput() int {
-	return -1
+	if !stm.explained {
+		return -1
+	}
+	return stm.numInput
+}
+
+// Columns implements the Columns() method of the PreparedStatement interface.
+func (stm *stmt) Columns() []string {
+	return stm.columns
 }
 
 // Exec implements the Exec() method of the sql/driver.Stmt interface.
@@ -38,19 +71,155 @@ func (stm *stmt) Close() error {
 }
 
 // makeArgsMap converts a driver.Value slices into a named parameters map.
-// It also converts driver.Value if necessary to fit in the neo4j database.
 func makeArgsMap(args []driver.Value) map[string]interface{} {
 	params := make(map[string]interface{})
 	for i, v := range args {
-		// Neo4j databases using Bolt protocol do not support binary data. If we receive a byte slice,
-		// it probably comes from a custom type which implements MarshalPS so we don't want to encode it again.
-		// If some day Neo4j handles binary data, we might want to unmarshal the bytes, then if it's valid
-		// assume it must not be encoded, if it is not, assume it should be encoded. Or maybe there is a better
-		// way.
-		if b, ok := v.([]byte); ok {
-			v = rawBytes(b)
-		}
 		params[strconv.Itoa(i)] = v
 	}
 	return params
 }
+
+// convertValue converts a query parameter into a value encoded by packstream: the values implementing
+// packstream.Marshaler are kept as they are, the driver.Valuer implementations are replaced by their value, and the
+// maps with string keys, the structs and the slices are converted into map[string]interface{} and []interface{},
+// recursively. The other values are converted by driver.DefaultParameterConverter.
+func convertValue(v interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, nil
+	}
+	switch value := v.(type) {
+	case nil, bool, int64, float64, string, []byte, time.Time, packstream.Marshaler:
+		return v, nil
+	case types.Map:
+		return convertValue(map[string]interface{}(value))
+	case types.List:
+		return convertValue([]interface{}(value))
+	case driver.Valuer:
+		converted, err := value.Value()
+		if err != nil {
+			return nil, err
+		}
+		return convertValue(converted)
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		return convertValue(rv.Elem().Interface())
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("neoql: Unsupported map type %T, its keys must be strings", v)
+		}
+		m := make(map[string]interface{}, rv.Len())
+		for _, key := range rv.MapKeys() {
+			item, err := convertValue(rv.MapIndex(key).Interface())
+			if err != nil {
+				return nil, err
+			}
+			m[key.String()] = item
+		}
+		return m, nil
+	case reflect.Struct:
+		return convertStruct(rv)
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 && rv.Kind() == reflect.Slice {
+			return rv.Bytes(), nil
+		}
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			item, err := convertValue(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			list[i] = item
+		}
+		return list, nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
+
+// convertStruct converts the "rv" struct into a map of its exported fields, keyed by their name, or by the name set
+// with a `neoql:"name"` tag. The fields tagged with `neoql:"-"` are skipped. The fields of an embedded struct which is
+// not tagged are promoted, like with encoding/json, the fields of the outer struct taking precedence.
+func convertStruct(rv reflect.Value) (map[string]interface{}, error) {
+	var embedded []reflect.Value
+
+	m := make(map[string]interface{}, rv.NumField())
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		tag := field.Tag.Get("neoql")
+		if tag == "-" {
+			continue
+		}
+		if typ := field.Type; field.Anonymous && tag == "" && (typ.Kind() == reflect.Struct ||
+			typ.Kind() == reflect.Ptr && typ.Elem().Kind() == reflect.Struct) {
+			// A nil embedded pointer has no field to promote.
+			if fv := reflect.Indirect(rv.Field(i)); fv.IsValid() {
+				embedded = append(embedded, fv)
+			}
+			continue
+		} else if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		if tag != "" {
+			name = tag
+		}
+		item, err := convertValue(rv.Field(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		m[name] = item
+	}
+	for _, fv := range embedded {
+		promoted, err := convertStruct(fv)
+		if err != nil {
+			return nil, err
+		}
+		for name, item := range promoted {
+			if _, ok := m[name]; !ok {
+				m[name] = item
+			}
+		}
+	}
+	return m, nil
+}
+
+// valuesToNamedValues converts driver.Value slices into ordinal driver.NamedValue slices.
+func valuesToNamedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+// namedValuesToParams converts driver.NamedValue slices into a parameters map. The parameters are keyed by their
+// name if they are all named with sql.Named, or else by their position, starting at 0. It returns ErrMixedArgs if
+// only some of them are named.
+func namedValuesToParams(args []driver.NamedValue) (map[string]interface{}, error) {
+	named := 0
+	for _, arg := range args {
+		if arg.Name != "" {
+			named++
+		}
+	}
+	if named == 0 {
+		values := make([]driver.Value, len(args))
+		for i, arg := range args {
+			values[i] = arg.Value
+		}
+		return makeArgsMap(values), nil
+	} else if named != len(args) {
+		return nil, ErrMixedArgs
+	}
+
+	params := make(map[string]interface{}, len(args))
+	for _, arg := range args {
+		if _, ok := params[arg.Name]; ok {
+			return nil, fmt.Errorf("query: Parameter %q is passed twice", arg.Name)
+		}
+		params[arg.Name] = arg.Value
+	}
+	return params, nil
+}
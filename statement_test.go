@@ -3,9 +3,11 @@ package neoql
 import (
 	"bytes"
 	"database/sql/driver"
+	"gopkg.in/neoql.v1/types"
 	"gopkg.in/packstream.v1"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestStmt_Close(t *testing.T) {
@@ -57,9 +59,8 @@ func TestStmt_Exec(t *testing.T) {
 	stm.conn = testMockConn(t, &rd, &wr)
 	stm.query = "CREATE (n {username: {0}})"
 	data := testGetEncodedMessage(t, packstream.NewStructure(byteRun, stm.query, map[string]interface{}{"0": "Bruce"}))
-	data = append(data, testGetEncodedMessage(t, packstream.NewStructure(bytePullAll))...)
+	data = append(data, testGetEncodedMessage(t, packstream.NewStructure(byteDiscardAll))...)
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{"testField"}})))
-	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteRecord, []interface{}{"testField1"})))
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
 	if _, err := stm.Exec([]driver.Value{"Bruce"}); err != nil {
 		t.Error(err)
@@ -76,3 +77,107 @@ func TestMakeArgs(t *testing.T) {
 		t.Errorf("invalid map received, expected %v, got %v.", output, res)
 	}
 }
+
+func TestConvertValue(t *testing.T) {
+	type tag string
+	type base struct {
+		ID      int64
+		Created string
+	}
+	type Audit struct {
+		Author string
+	}
+	type post struct {
+		base
+		*Audit
+		Meta  base `neoql:"meta"`
+		Title string
+		ID    string
+	}
+	type user struct {
+		ID       int64
+		Name     string `neoql:"name"`
+		Tags     []string
+		Friends  []user
+		Password string `neoql:"-"`
+		hidden   bool
+	}
+	var nilMap *types.Map
+	for _, test := range []struct {
+		input    interface{}
+		expected interface{}
+	}{
+		{nil, nil},
+		{nilMap, nil},
+		{42, int64(42)},
+		{float32(0.5), float64(0.5)},
+		{tag("a"), "a"},
+		{[]byte{0x01, 0x02}, []byte{0x01, 0x02}},
+		{[]string{"a", "b"}, []interface{}{"a", "b"}},
+		{[2]int64{1, 2}, []interface{}{int64(1), int64(2)}},
+		{map[string][]int{"a": {1}}, map[string]interface{}{"a": []interface{}{int64(1)}}},
+		{types.Map{"a": []string{"b"}}, map[string]interface{}{"a": []interface{}{"b"}}},
+		{types.List{1, types.Map{}}, []interface{}{int64(1), map[string]interface{}{}}},
+		{user{ID: 1, Name: "a", Tags: []string{"b"}, Password: "c", hidden: true}, map[string]interface{}{"ID": int64(1), "name": "a", "Tags": []interface{}{"b"}, "Friends": []interface{}{}}},
+		{post{base: base{ID: 1, Created: "a"}, Audit: &Audit{Author: "b"}, Meta: base{ID: 2}, Title: "c", ID: "d"}, map[string]interface{}{
+			"ID": "d", "Created": "a", "Author": "b", "Title": "c", "meta": map[string]interface{}{"ID": int64(2), "Created": ""},
+		}},
+		{post{Title: "c"}, map[string]interface{}{"ID": "", "Created": "", "Title": "c", "meta": map[string]interface{}{"ID": int64(0), "Created": ""}}},
+		{&user{Friends: []user{{ID: 2}}}, map[string]interface{}{"ID": int64(0), "name": "", "Tags": []interface{}{}, "Friends": []interface{}{
+			map[string]interface{}{"ID": int64(2), "name": "", "Tags": []interface{}{}, "Friends": []interface{}{}},
+		}}},
+	} {
+		if output, err := convertValue(test.input); err != nil {
+			t.Error(err)
+		} else if !reflect.DeepEqual(output, test.expected) {
+			t.Errorf("invalid value for %#v, expected %#v got %#v.", test.input, test.expected, output)
+		}
+	}
+
+	tm := types.Time{Time: time.Now()}
+	if output, err := convertValue(tm); err != nil {
+		t.Error(err)
+	} else if output != tm {
+		t.Errorf("a packstream.Marshaler should not be converted, got %#v.", output)
+	}
+	if _, err := convertValue(map[int]string{1: "a"}); err == nil {
+		t.Error("error should not be nil when the map keys are not strings.")
+	}
+	if _, err := convertValue(struct{ C chan int }{}); err == nil {
+		t.Error("error should not be nil when a struct field is not supported.")
+	}
+
+	nv := driver.NamedValue{Ordinal: 1, Value: []string{"a"}}
+	if err := new(conn).CheckNamedValue(&nv); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(nv.Value, []interface{}{"a"}) {
+		t.Errorf("invalid named value, got %#v.", nv.Value)
+	}
+}
+
+func TestNamedValuesToParams(t *testing.T) {
+	positional := []driver.NamedValue{{Ordinal: 1, Value: "a"}, {Ordinal: 2, Value: "b"}}
+	expected := map[string]interface{}{"0": "a", "1": "b"}
+	if params, err := namedValuesToParams(positional); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(params, expected) {
+		t.Errorf("invalid parameters, expected %v got %v.", expected, params)
+	}
+
+	named := []driver.NamedValue{{Name: "name", Ordinal: 1, Value: "bob"}, {Name: "age", Ordinal: 2, Value: int64(42)}}
+	expected = map[string]interface{}{"name": "bob", "age": int64(42)}
+	if params, err := namedValuesToParams(named); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(params, expected) {
+		t.Errorf("invalid parameters, expected %v got %v.", expected, params)
+	}
+
+	mixed := []driver.NamedValue{{Name: "name", Ordinal: 1, Value: "bob"}, {Ordinal: 2, Value: int64(42)}}
+	if _, err := namedValuesToParams(mixed); err != ErrMixedArgs {
+		t.Errorf("error should be %v, got %v.", ErrMixedArgs, err)
+	}
+	twice := []driver.NamedValue{{Name: "name", Ordinal: 1, Value: "bob"}, {Name: "name", Ordinal: 2, Value: "alice"}}
+	if _, err := namedValuesToParams(twice); err == nil {
+		t.Error("error should not be nil when a parameter is passed twice.")
+	}
+}
@@ -1,22 +1,58 @@
 package neoql
 
+import (
+	"context"
+	"gopkg.in/neoql.v1/types"
+	"gopkg.in/packstream.v1"
+)
+
 // tx implements the sql/driver.Tx interface.
 type tx struct {
 	conn *conn
 }
 
 // Commit implements the Commit() method of the sql/driver.Tx interface.
-// It runs a "COMMIT" Cypher query.
+// On Bolt v3 and above, it sends a COMMIT message and keeps the bookmark of the transaction. On Bolt v1, it runs a "COMMIT" Cypher query.
 func (tx *tx) Commit() (err error) {
 	tx.conn.tx = nil
-	_, err = tx.conn.run("COMMIT", map[string]interface{}{})
-	return
+	if !tx.conn.atLeast(3, 0) {
+		_, err = tx.conn.exec(context.Background(), "COMMIT", map[string]interface{}{})
+		return
+	}
+	if err = tx.conn.ready(); err != nil {
+		return
+	}
+	if tx.conn.state != stateTxReady {
+		// The transaction has been rolled back while recovering from a failure.
+		return ErrTransactionFailed
+	}
+	return tx.end(byteCommit)
 }
 
 // Rollback implements the Rollback() method of the sql/driver.Tx interface.
-// It runs a "ROLLBACK" Cypher query.
+// On Bolt v3 and above, it sends a ROLLBACK message, unless the transaction has already been rolled back because of a
+// failure. On Bolt v1, it runs a "ROLLBACK" Cypher query.
 func (tx *tx) Rollback() (err error) {
 	tx.conn.tx = nil
-	_, err = tx.conn.run("ROLLBACK", map[string]interface{}{})
-	return
+	if !tx.conn.atLeast(3, 0) {
+		_, err = tx.conn.exec(context.Background(), "ROLLBACK", map[string]interface{}{})
+		return
+	}
+	if err = tx.conn.ready(); err != nil || tx.conn.state != stateTxReady {
+		return
+	}
+	return tx.end(byteRollback)
+}
+
+// end sends the COMMIT or ROLLBACK message "signature" to end the transaction, on Bolt v3 and above.
+// Once committed, the transaction bookmark is handed to the bookmark manager.
+func (tx *tx) end(signature byte) error {
+	if res, err := tx.conn.request(packstream.NewStructure(signature)); err != nil {
+		return err
+	} else if res.Signature != byteSuccess {
+		return messageError(res, types.ErrProtocol)
+	} else if signature == byteCommit {
+		tx.conn.receiveBookmark(res)
+	}
+	return nil
 }
@@ -20,7 +20,7 @@ func TestTx_Commit(t *testing.T) {
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{}})))
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
 	data := testGetEncodedMessage(t, packstream.NewStructure(byteRun, "COMMIT", map[string]interface{}{}))
-	data = append(data, testGetEncodedMessage(t, packstream.NewStructure(bytePullAll))...)
+	data = append(data, testGetEncodedMessage(t, packstream.NewStructure(byteDiscardAll))...)
 	if err := txx.Commit(); err != nil {
 		t.Error(err)
 	} else if !bytes.Equal(wr.Bytes(), data) {
@@ -42,10 +42,67 @@ func TestTx_Rollback(t *testing.T) {
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"fields": []interface{}{}})))
 	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
 	data := testGetEncodedMessage(t, packstream.NewStructure(byteRun, "ROLLBACK", map[string]interface{}{}))
-	data = append(data, testGetEncodedMessage(t, packstream.NewStructure(bytePullAll))...)
+	data = append(data, testGetEncodedMessage(t, packstream.NewStructure(byteDiscardAll))...)
 	if err := txx.Rollback(); err != nil {
 		t.Error(err)
 	} else if !bytes.Equal(wr.Bytes(), data) {
 		t.Errorf("Unexpected rollback message, expected %# x got %# x.", data, wr.Bytes())
 	}
 }
+
+func TestTx_messages(t *testing.T) {
+	var (
+		wr bytes.Buffer
+		rd bytes.Buffer
+	)
+	c := testMockConn(t, &rd, &wr)
+	defer c.Close()
+	c.version = 3
+
+	// A successful commit.
+	c.state = stateTxReady
+	txx := &tx{conn: c}
+	c.tx = txx
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{"bookmark": "bm"})))
+	data := testGetEncodedMessage(t, packstream.NewStructure(byteCommit))
+	if err := txx.Commit(); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(wr.Bytes(), data) {
+		t.Errorf("Unexpected commit message, expected %# x got %# x.", data, wr.Bytes())
+	} else if c.state != stateReady {
+		t.Errorf("state should be %v, got %v.", stateReady, c.state)
+	} else if c.tx != nil {
+		t.Errorf("transaction should be nil, got %v.", c.tx)
+	}
+
+	// A successful rollback.
+	wr.Reset()
+	c.state = stateTxReady
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	data = testGetEncodedMessage(t, packstream.NewStructure(byteRollback))
+	if err := txx.Rollback(); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(wr.Bytes(), data) {
+		t.Errorf("Unexpected rollback message, expected %# x got %# x.", data, wr.Bytes())
+	}
+
+	// A failed transaction is reset, so there is nothing left to roll back, and it cannot be committed.
+	for _, end := range []func() error{txx.Rollback, txx.Commit} {
+		wr.Reset()
+		c.state = stateFailed
+		rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+		data = testGetEncodedMessage(t, packstream.NewStructure(byteReset))
+		if err := end(); err != nil && err != ErrTransactionFailed {
+			t.Error(err)
+		} else if !bytes.Equal(wr.Bytes(), data) {
+			t.Errorf("Unexpected messages, expected %# x got %# x.", data, wr.Bytes())
+		} else if c.state != stateReady {
+			t.Errorf("state should be %v, got %v.", stateReady, c.state)
+		}
+	}
+	c.state = stateFailed
+	rd.Write(testGetEncodedMessage(t, packstream.NewStructure(byteSuccess, map[string]interface{}{})))
+	if err := txx.Commit(); err != ErrTransactionFailed {
+		t.Errorf("error should be %v, got %v.", ErrTransactionFailed, err)
+	}
+}
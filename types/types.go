@@ -11,6 +11,13 @@ interface so they can be used as parameter during calls to "Query()" or "Scan()"
 	Map 		Can be scanned		Can be a query parameter
 	List 		Can be scanned		Can be a query parameter
 	Time		Can be scanned		Can be a query parameter
+	Date		Can be scanned		Can be a query parameter
+	OffsetTime	Can be scanned		Can be a query parameter
+	LocalTime	Can be scanned		Can be a query parameter
+	LocalDateTime	Can be scanned		Can be a query parameter
+	Duration	Can be scanned		Can't be a query parameter
+	Point2D		Can be scanned		Can't be a query parameter
+	Point3D		Can be scanned		Can't be a query parameter
 
 */
 package types
@@ -25,6 +32,7 @@ import (
 // Entity contains the common fields used by Node, UnboundRelationship and Relationship.
 type Entity struct {
 	ID         uint64                 // ID is the Neo4j entity ID.
+	ElementID  string                 // ElementID is the Neo4j entity element ID, set since Bolt v5.
 	Properties map[string]interface{} // Properties contains the entity properties.
 }
 
@@ -72,10 +80,12 @@ func (rs *UnboundRelationship) Scan(src interface{}) error {
 // Relationship represents a Neo4j relationship.
 type Relationship struct {
 	UnboundRelationship
-	FromID uint64
-	From   *Node
-	ToID   uint64
-	End    *Node
+	FromID        uint64
+	FromElementID string // FromElementID is the element ID of the "From" node, set since Bolt v5.
+	From          *Node
+	ToID          uint64
+	ToElementID   string // ToElementID is the element ID of the "To" node, set since Bolt v5.
+	End           *Node
 }
 
 // Scan implements the Scanner interface, so a Relationship can be used as a parameter to "Scan()".
@@ -187,13 +197,18 @@ func (t Time) MarshalPS() ([]byte, error) {
 	return packstream.Marshal(i)
 }
 
-// Scan implements the Scanner interface, so a List can be used as a parameter to "Scan()".
+// Scan implements the Scanner interface, so a Time can be used as a parameter to "Scan()". It accepts the
+// nanoseconds stored by MarshalPS, and the temporal values returned since Bolt v2.
 func (t *Time) Scan(src interface{}) error {
 	var (
 		i  int64
 		ok bool
 	)
-	if i, ok = src.(int64); !ok {
+	if tm, isTime := src.(time.Time); isTime {
+		t.Time = tm
+	} else if temporal, isTemporal := src.(interface{ Time() time.Time }); isTemporal {
+		t.Time = temporal.Time()
+	} else if i, ok = src.(int64); !ok {
 		return errors.New("failed to scan time")
 	} else if i == 0 {
 		t.Time = time.Time{}
@@ -202,3 +217,145 @@ func (t *Time) Scan(src interface{}) error {
 	}
 	return nil
 }
+
+// Date represents a Neo4j date, returned since Bolt v2, at midnight UTC. It can be scanned into a time.Time, and used
+// as a query parameter like a time.Time.
+type Date time.Time
+
+// Time returns the date as a time.Time.
+func (d Date) Time() time.Time {
+	return time.Time(d)
+}
+
+// String returns the date formatted like "2006-01-02".
+func (d Date) String() string {
+	return time.Time(d).Format("2006-01-02")
+}
+
+// Value implements the sql/driver.Valuer interface.
+func (d Date) Value() (driver.Value, error) {
+	return time.Time(d), nil
+}
+
+// OffsetTime represents a Neo4j time with a zone offset, returned since Bolt v2, on January 1 of year 0. It can be
+// scanned into a time.Time, and used as a query parameter like a time.Time.
+type OffsetTime time.Time
+
+// Time returns the time as a time.Time.
+func (t OffsetTime) Time() time.Time {
+	return time.Time(t)
+}
+
+// String returns the time formatted like "15:04:05.999999999Z07:00".
+func (t OffsetTime) String() string {
+	return time.Time(t).Format("15:04:05.999999999Z07:00")
+}
+
+// Value implements the sql/driver.Valuer interface.
+func (t OffsetTime) Value() (driver.Value, error) {
+	return time.Time(t), nil
+}
+
+// LocalTime represents a Neo4j local time, returned since Bolt v2, on January 1 of year 0 UTC. It can be scanned into
+// a time.Time, and used as a query parameter like a time.Time.
+type LocalTime time.Time
+
+// Time returns the local time as a time.Time.
+func (t LocalTime) Time() time.Time {
+	return time.Time(t)
+}
+
+// String returns the local time formatted like "15:04:05.999999999".
+func (t LocalTime) String() string {
+	return time.Time(t).Format("15:04:05.999999999")
+}
+
+// Value implements the sql/driver.Valuer interface.
+func (t LocalTime) Value() (driver.Value, error) {
+	return time.Time(t), nil
+}
+
+// LocalDateTime represents a Neo4j local date time, returned since Bolt v2, in UTC. It can be scanned into a
+// time.Time, and used as a query parameter like a time.Time.
+type LocalDateTime time.Time
+
+// Time returns the local date time as a time.Time.
+func (t LocalDateTime) Time() time.Time {
+	return time.Time(t)
+}
+
+// String returns the local date time formatted like "2006-01-02T15:04:05.999999999".
+func (t LocalDateTime) String() string {
+	return time.Time(t).Format("2006-01-02T15:04:05.999999999")
+}
+
+// Value implements the sql/driver.Valuer interface.
+func (t LocalDateTime) Value() (driver.Value, error) {
+	return time.Time(t), nil
+}
+
+// Duration represents a Neo4j duration, returned since Bolt v2. Its components are kept apart, since the length of a
+// month or a day varies.
+type Duration struct {
+	Months      int64
+	Days        int64
+	Seconds     int64
+	Nanoseconds int64
+}
+
+// Scan implements the Scanner interface, so a Duration can be used as a parameter to "Scan()".
+func (d *Duration) Scan(src interface{}) error {
+	var (
+		res *Duration
+		ok  bool
+	)
+
+	if res, ok = src.(*Duration); !ok {
+		return errors.New("failed to scan duration")
+	}
+	*d = *res
+	return nil
+}
+
+// Point2D represents a Neo4j two-dimensional point, returned since Bolt v2.
+type Point2D struct {
+	SRID int64 // SRID is the identifier of the coordinate reference system, like 4326 for WGS-84.
+	X    float64
+	Y    float64
+}
+
+// Scan implements the Scanner interface, so a Point2D can be used as a parameter to "Scan()".
+func (p *Point2D) Scan(src interface{}) error {
+	var (
+		res *Point2D
+		ok  bool
+	)
+
+	if res, ok = src.(*Point2D); !ok {
+		return errors.New("failed to scan point")
+	}
+	*p = *res
+	return nil
+}
+
+// Point3D represents a Neo4j three-dimensional point, returned since Bolt v2.
+type Point3D struct {
+	SRID int64 // SRID is the identifier of the coordinate reference system, like 4979 for WGS-84 3D.
+	X    float64
+	Y    float64
+	Z    float64
+}
+
+// Scan implements the Scanner interface, so a Point3D can be used as a parameter to "Scan()".
+func (p *Point3D) Scan(src interface{}) error {
+	var (
+		res *Point3D
+		ok  bool
+	)
+
+	if res, ok = src.(*Point3D); !ok {
+		return errors.New("failed to scan point")
+	}
+	*p = *res
+	return nil
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"bytes"
+	"database/sql/driver"
 	"gopkg.in/packstream.v1"
 	"testing"
 	"time"
@@ -188,4 +189,77 @@ func TestTime_Scan(t *testing.T) {
 	} else if !tm.IsZero() {
 		t.Errorf("unexpected time value, got %v expected zero time.", tm)
 	}
+	if err := tm.Scan(now); err != nil {
+		t.Error(err)
+	} else if !tm.Equal(now) {
+		t.Errorf("unexpected time value, got %v expected %v.", tm, now)
+	}
+	if err := tm.Scan(LocalDateTime(now)); err != nil {
+		t.Error(err)
+	} else if !tm.Equal(now) {
+		t.Errorf("unexpected time value, got %v expected %v.", tm, now)
+	}
+}
+
+func TestTemporal(t *testing.T) {
+	tm := time.Date(2024, 3, 1, 12, 30, 15, 500, time.FixedZone("", 3600))
+	for _, test := range []struct {
+		value    interface {
+			Time() time.Time
+			String() string
+			Value() (driver.Value, error)
+		}
+		expected string
+	}{
+		{Date(tm), "2024-03-01"},
+		{OffsetTime(tm), "12:30:15.0000005+01:00"},
+		{LocalTime(tm), "12:30:15.0000005"},
+		{LocalDateTime(tm), "2024-03-01T12:30:15.0000005"},
+	} {
+		if s := test.value.String(); s != test.expected {
+			t.Errorf("invalid string, expected %v got %v.", test.expected, s)
+		} else if !test.value.Time().Equal(tm) {
+			t.Errorf("invalid time, expected %v got %v.", tm, test.value.Time())
+		} else if v, err := test.value.Value(); err != nil {
+			t.Error(err)
+		} else if v != tm {
+			t.Errorf("invalid value, expected %v got %v.", tm, v)
+		}
+	}
+}
+
+func TestDuration_Scan(t *testing.T) {
+	src := &Duration{Months: 1, Days: 2}
+	dst := new(Duration)
+
+	if err := dst.Scan(src); err != nil {
+		t.Error(err)
+	} else if *dst != *src {
+		t.Errorf("destination is %v and should be %v.", dst, src)
+	}
+
+	if err := dst.Scan(42); err == nil {
+		t.Error("error should not be nil when passing value 42.")
+	}
+}
+
+func TestPoint_Scan(t *testing.T) {
+	src2 := &Point2D{SRID: 7203, X: 1, Y: 2}
+	dst2 := new(Point2D)
+	src3 := &Point3D{SRID: 9157, X: 1, Y: 2, Z: 3}
+	dst3 := new(Point3D)
+
+	if err := dst2.Scan(src2); err != nil {
+		t.Error(err)
+	} else if *dst2 != *src2 {
+		t.Errorf("destination is %v and should be %v.", dst2, src2)
+	} else if err = dst3.Scan(src3); err != nil {
+		t.Error(err)
+	} else if *dst3 != *src3 {
+		t.Errorf("destination is %v and should be %v.", dst3, src3)
+	}
+
+	if err := dst2.Scan(src3); err == nil {
+		t.Error("error should not be nil when scanning a 3D point into a 2D point.")
+	}
 }
@@ -3,9 +3,11 @@ package neoql
 import (
 	"gopkg.in/neoql.v1/types"
 	"gopkg.in/packstream.v1"
+	"time"
 )
 
 // hydrateNode reads a packstream structure and hydrate a Node with its data.
+// Since Bolt v5, the structure has a fourth field which is the element ID.
 // If the structure does not represent a valid Node, it returns a types.ProtocolError.
 func hydrateNode(n *types.Node, st *packstream.Structure) error {
 	var (
@@ -16,7 +18,7 @@ func hydrateNode(n *types.Node, st *packstream.Structure) error {
 		convOK    bool
 	)
 
-	if len(st.Fields) != 3 {
+	if len(st.Fields) != 3 && len(st.Fields) != 4 {
 		return types.ErrProtocol
 	}
 
@@ -42,10 +44,18 @@ func hydrateNode(n *types.Node, st *packstream.Structure) error {
 		return types.ErrProtocol
 	}
 	n.Properties = props
+
+	// ElementID
+	if len(st.Fields) == 4 {
+		if n.ElementID, convOK = st.Fields[3].(string); !convOK {
+			return types.ErrProtocol
+		}
+	}
 	return nil
 }
 
 // hydrateUnboundRelationship reads a packstream structure and hydrate an UnboundRelationship with its data.
+// Since Bolt v5, the structure has a fourth field which is the element ID.
 // If the structure does not represent a valid UnboundRelationship, it returns a types.ProtocolError.
 func hydrateUnboundRelationship(rs *types.UnboundRelationship, st *packstream.Structure) error {
 	var (
@@ -55,7 +65,7 @@ func hydrateUnboundRelationship(rs *types.UnboundRelationship, st *packstream.St
 		props  map[string]interface{}
 	)
 
-	if len(st.Fields) != 3 {
+	if len(st.Fields) != 3 && len(st.Fields) != 4 {
 		return types.ErrProtocol
 	}
 
@@ -76,10 +86,19 @@ func hydrateUnboundRelationship(rs *types.UnboundRelationship, st *packstream.St
 		return types.ErrProtocol
 	}
 	rs.Properties = props
+
+	// ElementID
+	if len(st.Fields) == 4 {
+		if rs.ElementID, convOK = st.Fields[3].(string); !convOK {
+			return types.ErrProtocol
+		}
+	}
 	return nil
 }
 
 // hydrateRelationship reads a packstream structure and hydrate an UnboundRelationship with its data.
+// Since Bolt v5, the structure has three more fields which are the element IDs of the relationship, the "From" node
+// and the "To" node.
 // If the structure does not represent a valid Relationship, it returns a types.ProtocolError.
 func hydrateRelationship(rs *types.Relationship, st *packstream.Structure) error {
 	var (
@@ -91,7 +110,7 @@ func hydrateRelationship(rs *types.Relationship, st *packstream.Structure) error
 		props  map[string]interface{}
 	)
 
-	if len(st.Fields) != 5 {
+	if len(st.Fields) != 5 && len(st.Fields) != 8 {
 		return types.ErrProtocol
 	}
 
@@ -124,6 +143,19 @@ func hydrateRelationship(rs *types.Relationship, st *packstream.Structure) error
 		return types.ErrProtocol
 	}
 	rs.Properties = props
+
+	// Element IDs
+	if len(st.Fields) == 8 {
+		if rs.ElementID, convOK = st.Fields[5].(string); !convOK {
+			return types.ErrProtocol
+		}
+		if rs.FromElementID, convOK = st.Fields[6].(string); !convOK {
+			return types.ErrProtocol
+		}
+		if rs.ToElementID, convOK = st.Fields[7].(string); !convOK {
+			return types.ErrProtocol
+		}
+	}
 	return nil
 }
 
@@ -183,8 +215,7 @@ func hydratePath(p *types.Path, st *packstream.Structure) error {
 			return types.ErrProtocol
 		}
 		p.Relationships[i] = new(types.Relationship)
-		p.Relationships[i].ID = rs.ID
-		p.Relationships[i].Properties = rs.Properties
+		p.Relationships[i].UnboundRelationship = *rs
 	}
 
 	// Sequence
@@ -218,16 +249,20 @@ func hydratePath(p *types.Path, st *packstream.Structure) error {
 				return types.ErrProtocol
 			}
 			p.Relationships[relIndex-1].FromID = lastNode.ID
+			p.Relationships[relIndex-1].FromElementID = lastNode.ElementID
 			p.Relationships[relIndex-1].From = lastNode
 			p.Relationships[relIndex-1].ToID = nextNode.ID
+			p.Relationships[relIndex-1].ToElementID = nextNode.ElementID
 			p.Relationships[relIndex-1].End = nextNode
 		} else {
 			if -relIndex-1 > int64(len(p.Relationships)) {
 				return types.ErrProtocol
 			}
 			p.Relationships[-relIndex-1].FromID = nextNode.ID
+			p.Relationships[-relIndex-1].FromElementID = nextNode.ElementID
 			p.Relationships[-relIndex-1].From = nextNode
 			p.Relationships[-relIndex-1].ToID = lastNode.ID
+			p.Relationships[-relIndex-1].ToElementID = lastNode.ElementID
 			p.Relationships[-relIndex-1].End = lastNode
 		}
 		lastNode = nextNode
@@ -235,6 +270,129 @@ func hydratePath(p *types.Path, st *packstream.Structure) error {
 	return nil
 }
 
+// hydrateTime reads a packstream structure representing a temporal value, returned since Bolt v2, and converts it to a
+// time.Time. A date is at midnight UTC, a time or a local time is on January 1 of year 0, and the local values are in
+// UTC. The legacy date times, before Bolt v5, carry their local seconds, the other ones their UTC seconds.
+// If the structure does not represent a valid temporal value, it returns a types.ProtocolError.
+func hydrateTime(st *packstream.Structure) (time.Time, error) {
+	var (
+		ints   []int64
+		zoneID string
+	)
+
+	// The fields are integers, but the zone ID of a date time, which is its third field.
+	for i, field := range st.Fields {
+		if n, ok := field.(int64); ok {
+			ints = append(ints, n)
+		} else if id, ok := field.(string); ok && i == 2 && (st.Signature == 'f' || st.Signature == 'i') {
+			zoneID = id
+		} else {
+			return time.Time{}, types.ErrProtocol
+		}
+	}
+
+	switch {
+	case st.Signature == 'D' && len(ints) == 1:
+		return time.Unix(ints[0]*86400, 0).UTC(), nil
+	case st.Signature == 't' && len(ints) == 1:
+		return time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(ints[0])), nil
+	case st.Signature == 'T' && len(ints) == 2:
+		zone := time.FixedZone("", int(ints[1]))
+		return time.Date(0, 1, 1, 0, 0, 0, 0, zone).Add(time.Duration(ints[0])), nil
+	case st.Signature == 'd' && len(ints) == 2:
+		return time.Unix(ints[0], ints[1]).UTC(), nil
+	case (st.Signature == 'F' || st.Signature == 'I') && len(ints) == 3:
+		zone := time.FixedZone("", int(ints[2]))
+		if st.Signature == 'F' {
+			return localTime(ints[0], ints[1], zone), nil
+		}
+		return time.Unix(ints[0], ints[1]).In(zone), nil
+	case (st.Signature == 'f' || st.Signature == 'i') && len(ints) == 2 && zoneID != "":
+		zone, err := time.LoadLocation(zoneID)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if st.Signature == 'f' {
+			return localTime(ints[0], ints[1], zone), nil
+		}
+		return time.Unix(ints[0], ints[1]).In(zone), nil
+	}
+	return time.Time{}, types.ErrProtocol
+}
+
+// hydrateTemporal reads a packstream structure representing a temporal value, and returns it with its Cypher kind: a
+// types.Date, types.OffsetTime, types.LocalTime or types.LocalDateTime, or a time.Time for a date time.
+func hydrateTemporal(st *packstream.Structure) (interface{}, error) {
+	tm, err := hydrateTime(st)
+	if err != nil {
+		return nil, err
+	}
+	switch st.Signature {
+	case 'D':
+		return types.Date(tm), nil
+	case 'T':
+		return types.OffsetTime(tm), nil
+	case 't':
+		return types.LocalTime(tm), nil
+	case 'd':
+		return types.LocalDateTime(tm), nil
+	}
+	return tm, nil
+}
+
+// localTime returns the time in "zone" whose wall clock is "seconds" and "nanoseconds" elapsed since the epoch.
+func localTime(seconds, nanoseconds int64, zone *time.Location) time.Time {
+	t := time.Unix(seconds, nanoseconds).UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), zone)
+}
+
+// hydrateDuration reads a packstream structure and hydrate a Duration with its data.
+// If the structure does not represent a valid Duration, it returns a types.ProtocolError.
+func hydrateDuration(d *types.Duration, st *packstream.Structure) error {
+	var convOK bool
+
+	if len(st.Fields) != 4 {
+		return types.ErrProtocol
+	}
+	for i, dst := range []*int64{&d.Months, &d.Days, &d.Seconds, &d.Nanoseconds} {
+		if *dst, convOK = st.Fields[i].(int64); !convOK {
+			return types.ErrProtocol
+		}
+	}
+	return nil
+}
+
+// hydratePoint reads a packstream structure and returns the Point2D or the Point3D it represents.
+// If the structure does not represent a valid point, it returns a types.ProtocolError.
+func hydratePoint(st *packstream.Structure) (interface{}, error) {
+	var (
+		srid   int64
+		coords []float64
+		convOK bool
+	)
+
+	if len(st.Fields) < 3 {
+		return nil, types.ErrProtocol
+	}
+	if srid, convOK = st.Fields[0].(int64); !convOK {
+		return nil, types.ErrProtocol
+	}
+	for _, field := range st.Fields[1:] {
+		coord, ok := field.(float64)
+		if !ok {
+			return nil, types.ErrProtocol
+		}
+		coords = append(coords, coord)
+	}
+	switch {
+	case st.Signature == 'X' && len(coords) == 2:
+		return &types.Point2D{SRID: srid, X: coords[0], Y: coords[1]}, nil
+	case st.Signature == 'Y' && len(coords) == 3:
+		return &types.Point3D{SRID: srid, X: coords[0], Y: coords[1], Z: coords[2]}, nil
+	}
+	return nil, types.ErrProtocol
+}
+
 // recordToType tries to convert a value to a type from the types subpackage : If the value is a packstream Structure,
 // it calls structRecordToType, if it is a slice or a map, it recursively calls recordToType for each value .
 func recordToType(v interface{}) (interface{}, error) {
@@ -268,10 +426,22 @@ func recordToType(v interface{}) (interface{}, error) {
 }
 
 // structRecordToType tries to convert a packstream Structure to a Node, Relationship, UnboundRelationship or a Path.
+// Since Bolt v2, the temporal values are converted to time.Time, the durations to Duration, and the points to Point2D
+// or Point3D.
 func structRecordToType(st *packstream.Structure) (_ interface{}, err error) {
 	switch st.Signature {
 	default:
 		return nil, types.ErrProtocol
+	case 'D', 't', 'T', 'd', 'F', 'f', 'I', 'i':
+		return hydrateTemporal(st)
+	case 'E':
+		res := new(types.Duration)
+		if err = hydrateDuration(res, st); err != nil {
+			return nil, err
+		}
+		return res, nil
+	case 'X', 'Y':
+		return hydratePoint(st)
 	case byte("N"[0]):
 		res := new(types.Node)
 		if err = hydrateNode(res, st); err != nil {
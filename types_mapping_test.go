@@ -5,6 +5,7 @@ import (
 	"gopkg.in/packstream.v1"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestRecordToType(t *testing.T) {
@@ -43,6 +44,118 @@ func TestStructRecordToType(t *testing.T) {
 	} else if _, ok := v.(*types.Node); !ok {
 		t.Error("returned value should be a node.")
 	}
+
+	if v, err := recordToType(*packstream.NewStructure('D', int64(0))); err != nil {
+		t.Error(err)
+	} else if _, ok := v.(types.Date); !ok {
+		t.Error("returned value should be a date.")
+	}
+}
+
+func TestHydrateTime(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	expected := time.Date(2024, 3, 1, 12, 30, 15, 42, paris)
+	_, offset := expected.Zone()
+	local := expected.Unix() + int64(offset)
+
+	for _, test := range []struct {
+		st       *packstream.Structure
+		expected time.Time
+	}{
+		{packstream.NewStructure('D', int64(19783)), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{packstream.NewStructure('t', int64(45015000000042)), time.Date(0, 1, 1, 12, 30, 15, 42, time.UTC)},
+		{packstream.NewStructure('T', int64(45015000000042), int64(3600)), time.Date(0, 1, 1, 12, 30, 15, 42, time.FixedZone("", 3600))},
+		{packstream.NewStructure('d', local, int64(42)), time.Date(2024, 3, 1, 12, 30, 15, 42, time.UTC)},
+		{packstream.NewStructure('F', local, int64(42), int64(offset)), expected},
+		{packstream.NewStructure('I', expected.Unix(), int64(42), int64(offset)), expected},
+		{packstream.NewStructure('f', local, int64(42), "Europe/Paris"), expected},
+		{packstream.NewStructure('i', expected.Unix(), int64(42), "Europe/Paris"), expected},
+	} {
+		if tm, err := hydrateTime(test.st); err != nil {
+			t.Error(err)
+		} else if !tm.Equal(test.expected) {
+			t.Errorf("invalid time of the %c structure, expected %v got %v.", test.st.Signature, test.expected, tm)
+		} else if tm.Format("15:04:05 -0700") != test.expected.Format("15:04:05 -0700") {
+			t.Errorf("invalid wall clock of the %c structure, expected %v got %v.", test.st.Signature, test.expected, tm)
+		}
+	}
+
+	// The temporal values keep their Cypher kind.
+	for _, test := range []struct {
+		st       *packstream.Structure
+		expected interface{}
+	}{
+		{packstream.NewStructure('D', int64(0)), types.Date(time.Unix(0, 0).UTC())},
+		{packstream.NewStructure('t', int64(0)), types.LocalTime(time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC))},
+		{packstream.NewStructure('d', int64(0), int64(0)), types.LocalDateTime(time.Unix(0, 0).UTC())},
+		{packstream.NewStructure('I', int64(0), int64(0), int64(0)), time.Unix(0, 0).In(time.FixedZone("", 0))},
+	} {
+		if v, err := hydrateTemporal(test.st); err != nil {
+			t.Error(err)
+		} else if reflect.TypeOf(v) != reflect.TypeOf(test.expected) {
+			t.Errorf("invalid type of the %c structure, expected %T got %T.", test.st.Signature, test.expected, v)
+		}
+	}
+	if v, err := hydrateTemporal(packstream.NewStructure('T', int64(0), int64(3600))); err != nil {
+		t.Error(err)
+	} else if _, ok := v.(types.OffsetTime); !ok {
+		t.Errorf("the T structure should be an offset time, got %T.", v)
+	}
+
+	// Failures
+	if _, err := hydrateTemporal(packstream.NewStructure('D', "string")); err == nil {
+		t.Error("error should not be nil when the date is not an integer.")
+	}
+	if _, err := hydrateTime(packstream.NewStructure('D', "string")); err == nil {
+		t.Error("error should not be nil when the date is not an integer.")
+	}
+	if _, err := hydrateTime(packstream.NewStructure('d', int64(1))); err == nil {
+		t.Error("error should not be nil when structure does not have enough fields.")
+	}
+	if _, err := hydrateTime(packstream.NewStructure('F', int64(1), int64(2), "Europe/Paris")); err == nil {
+		t.Error("error should not be nil when the offset is not an integer.")
+	}
+}
+
+func TestHydrateDuration(t *testing.T) {
+	d := new(types.Duration)
+	if err := hydrateDuration(d, packstream.NewStructure('E', int64(1), int64(2), int64(3), int64(4))); err != nil {
+		t.Error(err)
+	} else if *d != (types.Duration{Months: 1, Days: 2, Seconds: 3, Nanoseconds: 4}) {
+		t.Errorf("invalid duration, got %+v.", d)
+	}
+
+	// Failures
+	if err := hydrateDuration(d, packstream.NewStructure('E', int64(1), int64(2), int64(3))); err == nil {
+		t.Error("error should not be nil when structure does not have enough fields.")
+	}
+	if err := hydrateDuration(d, packstream.NewStructure('E', int64(1), int64(2), int64(3), 4.5)); err == nil {
+		t.Error("error should not be nil when a field is not an integer.")
+	}
+}
+
+func TestHydratePoint(t *testing.T) {
+	if p, err := hydratePoint(packstream.NewStructure('X', int64(7203), 1.5, 2.5)); err != nil {
+		t.Error(err)
+	} else if p2, ok := p.(*types.Point2D); !ok || *p2 != (types.Point2D{SRID: 7203, X: 1.5, Y: 2.5}) {
+		t.Errorf("invalid 2D point, got %+v.", p)
+	}
+	if p, err := hydratePoint(packstream.NewStructure('Y', int64(9157), 1.5, 2.5, 3.5)); err != nil {
+		t.Error(err)
+	} else if p3, ok := p.(*types.Point3D); !ok || *p3 != (types.Point3D{SRID: 9157, X: 1.5, Y: 2.5, Z: 3.5}) {
+		t.Errorf("invalid 3D point, got %+v.", p)
+	}
+
+	// Failures
+	if _, err := hydratePoint(packstream.NewStructure('X', int64(7203), 1.5, 2.5, 3.5)); err == nil {
+		t.Error("error should not be nil when a 2D point has three coordinates.")
+	}
+	if _, err := hydratePoint(packstream.NewStructure('Y', int64(9157), 1.5, "string", 3.5)); err == nil {
+		t.Error("error should not be nil when a coordinate is not a float.")
+	}
 }
 
 func TestHydrateNode(t *testing.T) {
@@ -59,6 +172,16 @@ func TestHydrateNode(t *testing.T) {
 		t.Errorf("node prop has invalid value, expected %v, got %v.", "value", prop)
 	}
 
+	// Bolt v5
+	if err := hydrateNode(node, packstream.NewStructure("N"[0], int64(42), []interface{}{"label"}, map[string]interface{}{"prop": "value"}, "4:db:42")); err != nil {
+		t.Error(err)
+	} else if node.ElementID != "4:db:42" {
+		t.Errorf("node has invalid element ID, expected %v, got %v", "4:db:42", node.ElementID)
+	}
+	if err := hydrateNode(node, packstream.NewStructure("N"[0], int64(42), []interface{}{"label"}, map[string]interface{}{"prop": "value"}, 42)); err == nil {
+		t.Error("error should not be nil when structure fourth field is not a string.")
+	}
+
 	if err := hydrateNode(node, packstream.NewStructure("N"[0], int64(42), []interface{}{"label"})); err == nil {
 		t.Error("error should not be nil when structure does not have enough fields.")
 	}
@@ -94,6 +217,23 @@ func TestHydrateRelationship(t *testing.T) {
 		t.Errorf("rs prop has invalid value, expected %v, got %v.", "value", prop)
 	}
 
+	// Bolt v5
+	if err := hydrateRelationship(rs, packstream.NewStructure("R"[0], int64(1), int64(2), int64(3), "label", map[string]interface{}{"prop": "value"}, "5:db:1", "4:db:2", "4:db:3")); err != nil {
+		t.Error(err)
+	} else if rs.ElementID != "5:db:1" {
+		t.Errorf("rs has invalid element ID, expected %v, got %v", "5:db:1", rs.ElementID)
+	} else if rs.FromElementID != "4:db:2" {
+		t.Errorf("rs has invalid From element ID, expected %v, got %v", "4:db:2", rs.FromElementID)
+	} else if rs.ToElementID != "4:db:3" {
+		t.Errorf("rs has invalid To element ID, expected %v, got %v", "4:db:3", rs.ToElementID)
+	}
+	if err := hydrateRelationship(rs, packstream.NewStructure("R"[0], int64(1), int64(2), int64(3), "label", map[string]interface{}{"prop": "value"}, "5:db:1", 2, "4:db:3")); err == nil {
+		t.Error("error should not be nil when structure field 7 is not a string.")
+	}
+	if err := hydrateRelationship(rs, packstream.NewStructure("R"[0], int64(1), int64(2), int64(3), "label", map[string]interface{}{"prop": "value"}, "5:db:1")); err == nil {
+		t.Error("error should not be nil when structure does not have all element IDs.")
+	}
+
 	if err := hydrateRelationship(rs, packstream.NewStructure("R"[0], int64(2), int64(3), "label", map[string]interface{}{"prop": "value"})); err == nil {
 		t.Error("error should not be nil when structure does not have enough fields.")
 	}
@@ -128,6 +268,13 @@ func TestHydrateUnboundRelationship(t *testing.T) {
 		t.Errorf("rs prop has invalid value, expected %v, got %v.", "value", prop)
 	}
 
+	// Bolt v5
+	if err := hydrateUnboundRelationship(rs, packstream.NewStructure("r"[0], int64(42), "label", map[string]interface{}{"prop": "value"}, "5:db:42")); err != nil {
+		t.Error(err)
+	} else if rs.ElementID != "5:db:42" {
+		t.Errorf("rs has invalid element ID, expected %v, got %v", "5:db:42", rs.ElementID)
+	}
+
 	if err := hydrateUnboundRelationship(rs, packstream.NewStructure("r"[0], int64(42), "label")); err == nil {
 		t.Error("error should not be nil when structure does not have enough fields.")
 	}
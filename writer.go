@@ -18,9 +18,11 @@ var (
 )
 
 // Writer is the implementation of the chunk writer for the Bolt protocol.
+// Several messages can be buffered with EndMessage, then written at once with Flush.
 type Writer struct {
-	wr io.Writer
-	b  *bytes.Buffer
+	wr      io.Writer
+	b       *bytes.Buffer
+	pending bytes.Buffer // chunks of the ended messages, not written to the underlying io.Writer yet.
 }
 
 // NewWriter returns a new chunk writer
@@ -52,9 +54,35 @@ func (cw *Writer) Write(p []byte) (n int, err error) {
 	return
 }
 
-// Flush writes any buffered data to the underlying io.Writer and prepends it with the chunk size. if zeroChunk is true,
-// it also writes a zero chunk size to the underlying io.Writer.
+// EndMessage ends the current message: buffered data is appended as a chunk to the pending messages, followed by a
+// zero chunk size. Nothing is written to the underlying io.Writer until Flush is called.
+func (cw *Writer) EndMessage() {
+	cw.chunk()
+	cw.pending.Write(chunkZero)
+}
+
+// Flush writes the pending messages and any buffered data to the underlying io.Writer, the buffered data being
+// prepended with the chunk size. if zeroChunk is true, it also writes a zero chunk size to the underlying io.Writer.
 func (cw *Writer) Flush(zeroChunk bool) (err error) {
+	cw.chunk()
+	if zeroChunk {
+		cw.pending.Write(chunkZero)
+	}
+	if cw.pending.Len() != 0 {
+		defer cw.pending.Reset()
+		_, err = cw.wr.Write(cw.pending.Bytes())
+	}
+	return
+}
+
+// discard drops the pending messages and any buffered data, without writing them.
+func (cw *Writer) discard() {
+	cw.b.Reset()
+	cw.pending.Reset()
+}
+
+// chunk appends buffered data to the pending messages, prepended with the chunk size.
+func (cw *Writer) chunk() {
 	var length int
 
 	if length = cw.b.Len(); length > sizeMaxChunk {
@@ -64,19 +92,8 @@ func (cw *Writer) Flush(zeroChunk bool) (err error) {
 	if length != 0 {
 		b := make([]byte, 2)
 		binary.BigEndian.PutUint16(b, uint16(length))
-		defer cw.b.Reset()
-
-		if _, err = cw.wr.Write(b); err != nil {
-			return
-		}
-		if _, err = cw.wr.Write(cw.b.Bytes()); err != nil {
-			return
-		}
+		cw.pending.Write(b)
+		cw.pending.Write(cw.b.Bytes())
+		cw.b.Reset()
 	}
-	if zeroChunk {
-		if _, err = cw.wr.Write(chunkZero); err != nil {
-			return
-		}
-	}
-	return
 }
@@ -64,3 +64,20 @@ func TestWriter_Flush(t *testing.T) {
 	}
 
 }
+
+func TestWriter_EndMessage(t *testing.T) {
+	b := new(bytes.Buffer)
+	wr := NewWriter(b)
+
+	wr.Write([]byte{42})
+	wr.EndMessage()
+	wr.Write([]byte{43, 44})
+	wr.EndMessage()
+	if b.Len() != 0 {
+		t.Errorf("ended messages should not be written before a flush, got %v.", b.Bytes())
+	} else if err := wr.Flush(false); err != nil {
+		t.Error(err)
+	} else if data := []byte{0, 1, 42, 0, 0, 0, 2, 43, 44, 0, 0}; !bytes.Equal(data, b.Bytes()) {
+		t.Errorf("invalid flush, got %v expected %v", b.Bytes(), data)
+	}
+}